@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/aaabramov/goal/lib"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var addDesc string
+var addAsserts []string
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add [GOAL [--on env] [--desc text] [--assert 'kind: value']... -- CMD [ARGS...]]",
+	Short: "Add goal to goal.yaml",
+	Long: `Adds goal to goal.yaml keeping comments and formatting of the file. Prompts for goal interactively when run without arguments.
+Adding a goal on env which the goal does not have yet adds the env to it.
+
+Example:
+  goal add apply --on dev --assert 'terraform_workspace: dev' --assert 'approval: yes' -- terraform apply -var-file vars/dev.tfvars`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var spec lib.GoalSpec
+		if len(args) == 0 {
+			spec = promptGoal()
+		} else {
+			dash := cmd.ArgsLenAtDash()
+			if dash != 1 || len(args) < 2 {
+				lib.Fatal("❗ Specify goal as 'goal add GOAL [flags] -- CMD [ARGS...]' or run 'goal add' to be prompted for it")
+			}
+			spec = lib.GoalSpec{Name: args[0], Env: env, Desc: addDesc, Cmd: args[1], Args: args[2:]}
+			for _, text := range addAsserts {
+				assert, err := lib.ParseAssert(text)
+				if err != nil {
+					lib.Fatal("❗ %s", err)
+				}
+				spec.Assert = append(spec.Assert, assert)
+			}
+		}
+		file := editableGoalFile()
+		editGoalFile(file, func(content []byte) ([]byte, error) {
+			return lib.AddGoal(content, spec)
+		})
+		goal := spec.Name
+		if spec.Env != "" {
+			goal += " on " + spec.Env
+		}
+		lib.Info("✅ Added %s to %s", goal, file)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+
+	addCmd.Flags().StringVarP(&env, "on", "e", "", "Environment to add goal on")
+	addCmd.Flags().StringVarP(&addDesc, "desc", "d", "", "Description of goal")
+	addCmd.Flags().StringArrayVarP(&addAsserts, "assert", "a", nil, "Assertion as YAML mapping, e.g. 'kubectl_context: dev' or '{ref: workspace, expect: dev}'")
+}
+
+// editableGoalFile returns goals file add and rm edit: the one given by -c or the nearest goal.yaml
+func editableGoalFile() string {
+	if goalFile != "" {
+		return goalFile
+	}
+	files := lib.FindGoalFiles(".")
+	if len(files) == 0 {
+		lib.Fatal("❗ No %s found in current directory or its parents up to repository root\n"+
+			"\t- run 'goal init' to generate goal.yaml file in current directory", lib.GoalFileName)
+	}
+	return files[0]
+}
+
+// editGoalFile rewrites file with edit, unless the result is invalid
+func editGoalFile(file string, edit func(content []byte) ([]byte, error)) {
+	info, err := os.Stat(file)
+	if err != nil {
+		lib.Fatal("❗ %s", err)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		lib.Fatal("❗ %s", err)
+	}
+	edited, err := edit(content)
+	if err != nil {
+		lib.Fatal("❗ %s", err)
+	}
+	if problems, _ := lib.ValidateBytes(file, edited); len(problems) > 0 {
+		msg := "❗ Goals file would become invalid, it is left unchanged:"
+		for _, problem := range problems {
+			msg += "\n\t" + problem.String()
+		}
+		lib.Fatal("%s", msg)
+	}
+	if err := ioutil.WriteFile(file, edited, info.Mode()); err != nil {
+		lib.Fatal("❗ Failed to write %s: %s", file, err)
+	}
+}
+
+// promptKinds are assertions goal add prompts a value for, other ones are typed as YAML
+var promptKinds = []string{"terraform_workspace", "kubectl_context", "kubectl_namespace", "gcloud_project", "tool_version", "ref", "approval", "other"}
+
+const promptDone = "done"
+
+// promptGoal asks for goal interactively
+func promptGoal() lib.GoalSpec {
+	required := func(input string) error {
+		if strings.TrimSpace(input) == "" {
+			return errors.New("value is required")
+		}
+		return nil
+	}
+	spec := lib.GoalSpec{
+		Name: prompt("Goal name", required),
+		Env:  prompt("Environment (empty for goal without environments)", nil),
+		Cmd:  prompt("Command", required),
+		Args: strings.Fields(prompt("Arguments", nil)),
+		Desc: prompt("Description", nil),
+	}
+	for {
+		selectKind := promptui.Select{Label: "Add assertion", Items: append([]string{promptDone}, promptKinds...)}
+		_, kind, err := selectKind.Run()
+		if err != nil {
+			lib.Fatal("❗ Prompt failed: %s", err)
+		}
+		var assert lib.YamlAssert
+		switch kind {
+		case promptDone:
+			return spec
+		case "approval":
+			assert = lib.YamlAssert{Approval: "yes"}
+		case "ref":
+			assert = lib.YamlAssert{Ref: prompt("Goal to run", required), Expect: prompt("Expected output", required)}
+		default:
+			var text string
+			if kind == "other" {
+				text = prompt("Assertion, e.g. 'env_var: {name: AWS_PROFILE, expect: dev}'", required)
+			} else {
+				text = kind + ": " + prompt(kind, required)
+			}
+			if assert, err = lib.ParseAssert(text); err != nil {
+				lib.Warn("❗ %s", err)
+				continue
+			}
+		}
+		spec.Assert = append(spec.Assert, assert)
+	}
+}
+
+func prompt(label string, validate promptui.ValidateFunc) string {
+	p := promptui.Prompt{Label: label, Validate: validate}
+	value, err := p.Run()
+	if err != nil {
+		lib.Fatal("❗ Prompt failed: %s", err)
+	}
+	return strings.TrimSpace(value)
+}
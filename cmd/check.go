@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"github.com/aaabramov/goal/lib"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var checkAll bool
+var checkOutput string
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [GOAL] [--on env] [--all]",
+	Short: "Check preconditions of goal without running it",
+	Long: `Evaluates assertions of goal on given environment (or on every environment when --on is omitted)
+and reports their status. Approval is never asked and reported as SKIP. Nothing is prompted for either:
+a blocked schedule with 'override: true' is reported as OVERRIDDEN with the reason from GOAL_OVERRIDE_REASON,
+and as FAIL when it is not set.
+
+Exit code is 0 when all assertions passed, 1 when some failed or were overridden and 2 when some could not be checked.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeGoals,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadGoals()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if checkOutput != "table" && checkOutput != "json" {
+			lib.Fatal("❗ Unsupported output: %s, expected one of [table, json]", checkOutput)
+		}
+		var goals []lib.Goal
+		if checkAll {
+			if len(args) > 0 {
+				lib.Fatal("❗ Either specify GOAL or --all")
+			}
+			goals = commands.Select("", "")
+		} else {
+			if len(args) == 0 {
+				_ = cmd.Help()
+				os.Exit(1)
+			}
+			goal := strings.TrimSpace(args[0])
+			if env != "" {
+				resolved, err := commands.Resolve(goal, env)
+				if err != nil {
+					lib.Fatal("❗ %s", err)
+				}
+				goals = []lib.Goal{*resolved}
+			} else {
+				name, err := commands.ResolveName(goal)
+				if err != nil {
+					lib.Fatal("❗ %s", err)
+				}
+				goals = commands.Select(name, "")
+			}
+		}
+		report := commands.Check(goals)
+		if checkOutput == "json" {
+			report.RenderJson()
+		} else {
+			report.Render()
+		}
+		os.Exit(report.ExitCode())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVarP(&env, "on", "e", "", "Environment to check, all environments of goal when omitted, example: goal check tf-apply --on dev")
+	_ = checkCmd.RegisterFlagCompletionFunc("on", completeEnvs)
+	checkCmd.Flags().BoolVarP(&checkAll, "all", "a", false, "Check every goal on every environment")
+	checkCmd.Flags().StringVarP(&checkOutput, "output", "o", "table", "Output format: table or json")
+}
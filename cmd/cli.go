@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"fmt"
 	"github.com/aaabramov/goal/lib"
 	"strings"
 
@@ -10,23 +9,19 @@ import (
 
 // cliCmd represents the cli command
 var cliCmd = &cobra.Command{
-	Use:   "cli GOAL [--on env]",
-	Short: "Show CLI for specific goal",
-	Args:  cobra.ExactArgs(1),
+	Use:               "cli GOAL [--on env]",
+	Short:             "Show CLI for specific goal",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeGoals,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		loadGoals()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		goal := args[0]
-		if cmd, exists := commands.GetWithEnv(strings.TrimSpace(goal), env); exists {
-			lib.Info(cmd.Cli())
-		} else {
-			msg := fmt.Sprintf("❗ No such goal: %s", goal)
-			if env != "" {
-				msg += fmt.Sprintf(" on env \"%s\"", env)
-			}
-			lib.Fatal(msg)
+		goal, err := commands.Resolve(strings.TrimSpace(args[0]), env)
+		if err != nil {
+			lib.Fatal("❗ %s", err)
 		}
+		lib.Info("%s", goal.Cli())
 	},
 }
 
@@ -34,4 +29,5 @@ func init() {
 	rootCmd.AddCommand(cliCmd)
 
 	cliCmd.Flags().StringVarP(&env, "on", "e", "", "Environment to use, example: goal cli tf-apply --on dev")
+	_ = cliCmd.RegisterFlagCompletionFunc("on", completeEnvs)
 }
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/aaabramov/goal/lib"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorOutput string
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Verify that tools and files used by goals are installed",
+	Long: `Lists executables used by goals and their assertions with resolved paths and versions,
+and files referenced in args (e.g. -var-file, -f, --values). Exits with 1 when anything is missing.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadGoals()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		report := commands.Doctor()
+		switch doctorOutput {
+		case "json":
+			report.RenderJson()
+		case "table":
+			report.Render()
+			lib.Info("%s", report.Summary())
+		default:
+			lib.Fatal("❗ Unsupported output: %s, expected one of [table, json]", doctorOutput)
+		}
+		if !report.Healthy() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "table", "Output format: table or json")
+}
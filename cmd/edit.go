@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/aaabramov/goal/lib"
+	"github.com/spf13/cobra"
+)
+
+var editDesc string
+var editAsserts []string
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit GOAL [--on env] [--desc text] [--assert 'kind: value']... [-- CMD [ARGS...]]",
+	Short: "Edit goal in goal.yaml",
+	Long: `Changes description, command or assertions of goal (or only of its env with --on) in goal.yaml keeping comments and
+formatting of the rest of the file. Command after -- replaces both cmd and args, --assert replaces all assertions.
+
+Example:
+  goal edit apply --on prod --assert 'terraform_workspace: prod' --assert 'approval: yes'
+  goal edit lint -- golangci-lint run --fix`,
+	ValidArgsFunction: completeGoals,
+	Run: func(cmd *cobra.Command, args []string) {
+		dash := cmd.ArgsLenAtDash()
+		if len(args) == 0 || dash == 0 || (dash < 0 && len(args) > 1) || (dash > 0 && (dash != 1 || len(args) < 2)) {
+			lib.Fatal("❗ Specify goal as 'goal edit GOAL [flags] [-- CMD [ARGS...]]'")
+		}
+		spec := lib.GoalSpec{Name: strings.TrimSpace(args[0]), Env: env, Desc: editDesc}
+		if dash == 1 {
+			spec.Cmd, spec.Args = args[1], args[2:]
+		}
+		for _, text := range editAsserts {
+			assert, err := lib.ParseAssert(text)
+			if err != nil {
+				lib.Fatal("❗ %s", err)
+			}
+			spec.Assert = append(spec.Assert, assert)
+		}
+		file := editableGoalFile()
+		editGoalFile(file, func(content []byte) ([]byte, error) {
+			return lib.EditGoal(content, spec)
+		})
+		goal := spec.Name
+		if spec.Env != "" {
+			goal += " on " + spec.Env
+		}
+		lib.Info("✅ Edited %s in %s", goal, file)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editCmd.Flags().StringVarP(&env, "on", "e", "", "Environment of goal to edit")
+	editCmd.Flags().StringVarP(&editDesc, "desc", "d", "", "New description of goal")
+	editCmd.Flags().StringArrayVarP(&editAsserts, "assert", "a", nil, "Assertion replacing existing ones, as YAML mapping, e.g. 'kubectl_context: dev'")
+	_ = editCmd.RegisterFlagCompletionFunc("on", completeEnvs)
+}
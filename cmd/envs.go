@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// envsCmd represents the envs command
+var envsCmd = &cobra.Command{
+	Use:   "envs",
+	Short: "List environments declared in goal.yaml",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		loadGoals()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		commands.RenderEnvironments()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envsCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/aaabramov/goal/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck bool
+	fmtShort bool
+)
+
+// fmtCmd represents the fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [FILE...] [--check] [--short]",
+	Short: "Format goals files",
+	Long: `Normalizes goals files keeping comments and order of goals: keys are ordered as desc, cmd, args, dir, assert, envs,
+indentation is 2 spaces and short lists of args are written in flow style, e.g. 'args: [plan, -var-file, vars/dev.tfvars]'.
+With --short long forms are replaced with short ones and cmd, args, desc and dir same in every env are moved to the goal.
+Formats the files goal would load when FILE is omitted. With --check files are not changed, it exits with 1 when any
+file is not formatted, e.g. in CI.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files := args
+		if len(files) == 0 {
+			files, _ = goalFiles()
+		}
+		unformatted := 0
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				lib.Fatal("❗ %s", err)
+			}
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				lib.Fatal("❗ %s", err)
+			}
+			formatted, err := lib.FormatGoals(content, fmtShort)
+			if err != nil {
+				lib.Fatal("❗ Failed to format %s: %s", file, err)
+			}
+			if bytes.Equal(content, formatted) {
+				continue
+			}
+			unformatted++
+			if fmtCheck {
+				lib.Warn("❌ %s is not formatted", file)
+				continue
+			}
+			if err := ioutil.WriteFile(file, formatted, info.Mode()); err != nil {
+				lib.Fatal("❗ Failed to write %s: %s", file, err)
+			}
+			lib.Info("✅ Formatted %s", file)
+		}
+		if fmtCheck && unformatted > 0 {
+			lib.Fatal("❗ %d file(s) are not formatted, run 'goal fmt' to format them", unformatted)
+		}
+		if unformatted == 0 {
+			lib.Info("✅ %d file(s) are formatted", len(files))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "Do not change files, exit with 1 when any file is not formatted")
+	fmtCmd.Flags().BoolVar(&fmtShort, "short", false, "Replace long forms with short ones")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaabramov/goal/lib"
+	"github.com/spf13/cobra"
+)
+
+var importFile string
+var importDryRun bool
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:       "import make|npm|task [--file FILE] [--dry-run]",
+	Short:     "Import goals from Makefile, package.json or Taskfile",
+	ValidArgs: lib.ImportSources,
+	Long: `Imports phony targets of Makefile, scripts of package.json or tasks of Taskfile.yml found in current directory as goals.
+Descriptions are taken from '## comments' of targets and desc of tasks. Single-line commands are ported as they are,
+other targets and tasks (e.g. with prerequisites or variables) are run by make or task.
+Imported goals are added to goal.yaml in current directory (or the one given by -c), goals it already defines are skipped.
+The file is created when it does not exist. With --dry-run imported goals are printed instead.`,
+	Args: cobra.ExactValidArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		source := args[0]
+		file := importFile
+		if file == "" {
+			var err error
+			if file, err = lib.FindImportFile(".", source); err != nil {
+				lib.Fatal("❗ %s", err)
+			}
+		}
+		specs, err := lib.ImportGoals(source, file)
+		if err != nil {
+			lib.Fatal("❗ Failed to import %s: %s", file, err)
+		}
+		if len(specs) == 0 {
+			lib.Fatal("❗ No goals found in %s", file)
+		}
+		target := goalFile
+		if target == "" {
+			target = lib.GoalFileName
+		}
+		if dir, err := filepath.Rel(filepath.Dir(target), filepath.Dir(file)); err == nil && dir != "." {
+			for i := range specs {
+				if !filepath.IsAbs(specs[i].Dir) {
+					specs[i].Dir = filepath.Join(dir, specs[i].Dir)
+				}
+			}
+		}
+		if importDryRun {
+			rendered, err := lib.RenderGoals(specs)
+			if err != nil {
+				lib.Fatal("❗ %s", err)
+			}
+			fmt.Print(string(rendered))
+			return
+		}
+		if _, err := os.Stat(target); err == nil {
+			added := 0
+			editGoalFile(target, func(content []byte) ([]byte, error) {
+				var edited []byte
+				edited, added, err = lib.AddGoals(content, specs)
+				return edited, err
+			})
+			lib.Info("✅ Imported %d goal(s) from %s to %s", added, file, target)
+			return
+		}
+		rendered, err := lib.RenderGoals(specs)
+		if err != nil {
+			lib.Fatal("❗ %s", err)
+		}
+		header := fmt.Sprintf("%s\n# Imported by `goal import %s` from %s.\n\n", lib.SchemaComment, source, strings.TrimPrefix(file, "./"))
+		if err := ioutil.WriteFile(target, append([]byte(header), rendered...), 0644); err != nil {
+			lib.Fatal("❗ Failed to create %s: %s", target, err)
+		}
+		lib.Info("✅ Imported %d goal(s) from %s to new %s", len(specs), file, target)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importFile, "file", "f", "", "Makefile, package.json or Taskfile to import instead of the one in current directory")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Print imported goals without changing goals file")
+}
@@ -1,151 +1,59 @@
 package cmd
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/aaabramov/goal/lib"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
-	"io/ioutil"
 )
 
+var initTemplates []string
+var initForce bool
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Create new goal.yaml file in current file",
-	Long:  "Create new goal.yaml file in current file",
+	Use:   "init [--template terraform,helm,...] [--force]",
+	Short: "Create new goal.yaml file in current directory",
+	Long: `Create new goal.yaml file in current directory with goals matched to the project.
+Project type is detected from files in current directory (*.tf, Chart.yaml, kustomization.yaml, go.mod, package.json)
+unless --template is specified. Environments are created per vars/*.tfvars and values/*.yaml files.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		initGoals("goal.yaml")
+		initGoals(lib.GoalFileName, initTemplates, initForce)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
-	// TODO: support templates
-}
 
-var defaultGoals = map[string]lib.YamlGoal{
-	"test": {
-		Desc:   "Run go tests",
-		Cmd:    "go",
-		Args:   []string{"test", "-v", "./..."},
-		Assert: nil,
-	},
-	"terraform-workspace": {
-		Desc:   "Current terraform workspace",
-		Cmd:    "terraform",
-		Args:   []string{"workspace", "show"},
-		Assert: nil,
-	},
-	"terraform-apply": {
-		Desc: "See https://github.com/aaabramov/goal/tree/master/examples/terraform",
-		Envs: &map[string]lib.YamlEnvGoal{
-			"dev": {
-				Desc: "Terraform apply on dev",
-				Cmd:  "terraform",
-				Args: []string{"apply", "-var-file", "vars/dev.tfvars"},
-				Assert: []lib.YamlAssert{
-					{
-						TerraformWorkspace: "dev",
-					}},
-			},
-			"stage": {
-				Desc: "Terraform apply on stage",
-				Cmd:  "terraform",
-				Args: []string{"apply", "-var-file", "vars/stage.tfvars"},
-				Assert: []lib.YamlAssert{
-					{
-						TerraformWorkspace: "stage",
-					},
-				},
-			},
-		},
-	},
-	"k8s-apply": {
-		Desc: "See https://github.com/aaabramov/goal/tree/master/examples/kubectl",
-		Envs: &map[string]lib.YamlEnvGoal{
-			"dev": {
-				Desc: "kubectl apply on dev",
-				Cmd:  "kubectl",
-				Args: []string{"apply", "-f", "deployment.yaml"},
-				Assert: []lib.YamlAssert{
-					{
-						KubectlContext: "gke_project_region_dev",
-					},
-					{
-						Approval: "yes",
-					},
-				},
-			},
-			"stage": {
-				Desc: "kubectl apply on stage",
-				Cmd:  "kubectl",
-				Args: []string{"apply", "-f", "deployment.yaml"},
-				Assert: []lib.YamlAssert{
-					{
-						KubectlContext: "gke_project_region_stage",
-					},
-					{
-						Approval: "yes",
-					},
-				},
-			},
-		},
-	},
-	"helm-upgrade": {
-		Desc: "See https://github.com/aaabramov/goal/tree/master/examples/helm",
-		Envs: &map[string]lib.YamlEnvGoal{
-			"dev": {
-				Desc: "helm upgrade on dev",
-				Cmd:  "helm",
-				Args: []string{"upgrade", "release-name", "-f", "values.yaml", "-f", "values/dev.yaml", "."},
-				Assert: []lib.YamlAssert{
-					{
-						KubectlContext: "gke_project_region_dev",
-					}},
-			},
-			"stage": {
-				Desc: "helm upgrade on stage",
-				Cmd:  "helm",
-				Args: []string{"upgrade", "release-name", "-f", "values.yaml", "-f", "values/stage.yaml", "."},
-				Assert: []lib.YamlAssert{
-					{
-						KubectlContext: "gke_project_region_stage",
-					}},
-			},
-		},
-	},
-	"gcloud-ssh": {
-		Desc: "See https://github.com/aaabramov/goal/tree/master/examples/gcloud",
-		Envs: &map[string]lib.YamlEnvGoal{
-			"dev": {
-				Desc: "SSH to dev",
-				Cmd:  "gcloud",
-				Args: []string{"compute", "ssh", "dev-vm", "--zone=us-central1-c"},
-				Assert: []lib.YamlAssert{
-					{
-						GcloudProject: "dev-project",
-					}},
-			},
-			"stage": {
-				Desc: "SSH to stage",
-				Cmd:  "gcloud",
-				Args: []string{"compute", "ssh", "stage-vm", "--zone=us-central1-c"},
-				Assert: []lib.YamlAssert{
-					{
-						GcloudProject: "stage-project",
-					}},
-			},
-		},
-	},
+	initCmd.Flags().StringSliceVarP(&initTemplates, "template", "t", nil, "Templates to generate goals from, one or more of ["+strings.Join(lib.TemplateNames, ", ")+"]")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite existing goals file")
 }
 
-func initGoals(filename string) {
-	lib.Info("⌛ Generating default %s file", filename)
-	bytes, err := yaml.Marshal(defaultGoals)
+func initGoals(filename string, templates []string, force bool) {
+	if _, err := os.Stat(filename); err == nil && !force {
+		lib.Fatal("❗ %s already exists, use --force to overwrite it", filename)
+	}
+	dir := filepath.Dir(filename)
+	if len(templates) == 0 {
+		templates = lib.DetectTemplates(dir)
+		if len(templates) == 0 {
+			lib.Info("⚠️  Could not detect project type, generating goals of every template")
+			templates = lib.TemplateNames
+		} else {
+			lib.Info("🔍 Detected project type: %s", strings.Join(templates, ", "))
+		}
+	}
+	lib.Info("⌛ Generating %s file", filename)
+	bytes, err := lib.GenerateGoals(dir, templates)
 	if err != nil {
-		lib.Fatal("❗ Failed to generate default YAML for goals")
+		lib.Fatal("❗ Failed to generate goals: %s", err)
 	}
 	if err = ioutil.WriteFile(filename, bytes, 0644); err != nil {
 		lib.Fatal("❗ Failed to create %s", filename)
 	}
-	lib.Info("✅ Generated default %s file. Try running `goal` to see available goals.", filename)
+	lib.Info("✅ Generated %s file. Try running `goal` to see available goals.", filename)
 }
@@ -1,31 +1,95 @@
 package cmd
 
 import (
-	"github.com/aaabramov/goal/lib"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/aaabramov/goal/lib"
 )
 
 func Test_initGoals(t *testing.T) {
-	type args struct {
-		filename string
-	}
 	tests := []struct {
-		name string
-		args args
+		name      string
+		files     map[string]string
+		templates []string
+		want      int
 	}{
-		{name: "generate file", args: args{filename: "goal.test.yaml"}},
+		{name: "every template when nothing detected", want: 15},
+		{name: "explicit template", templates: []string{"go"}, want: 3},
+		{
+			name:  "terraform env per tfvars",
+			files: map[string]string{"main.tf": "", "vars/dev.tfvars": "", "vars/prod.tfvars": ""},
+			want:  5,
+		},
+		{
+			name:  "go and node",
+			files: map[string]string{"go.mod": "module x", "package.json": `{"scripts": {"lint": "eslint .", "build": "tsc"}}`},
+			want:  6,
+		},
+		{
+			name:  "helm env per values file",
+			files: map[string]string{"Chart.yaml": "name: app", "values.yaml": "", "values/dev.yaml": "", "values/stage.yaml": ""},
+			want:  3,
+		},
+		{
+			name:  "overwrite with force",
+			files: map[string]string{"goal.yaml": "old: {cmd: echo}", "go.mod": "module x"},
+			want:  3,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			initGoals(tt.args.filename)
-			bytes, _ := ioutil.ReadFile(tt.args.filename)
-			commands, _ := lib.ParseCommands(bytes)
-			if len(commands.Commands) != 10 {
-				t.Errorf("expected %d commands to be generated, got: %d", 10, len(commands.Commands))
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				_ = os.MkdirAll(filepath.Dir(path), 0755)
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			filename := filepath.Join(dir, "goal.yaml")
+			initGoals(filename, tt.templates, true)
+			if problems := lib.ValidateFile(filename); len(problems) > 0 {
+				t.Errorf("generated goals are invalid: %v", problems)
+			}
+			bytes, _ := ioutil.ReadFile(filename)
+			commands, err := lib.ParseCommands(bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(commands.Commands) != tt.want {
+				t.Errorf("expected %d commands to be generated, got: %d", tt.want, len(commands.Commands))
 			}
-			_ = os.Remove(tt.args.filename)
 		})
 	}
 }
+
+func Test_initGoals_existingFile(t *testing.T) {
+	// initGoals exits on failure, so it is run in a subprocess
+	if dir := os.Getenv("GOAL_TEST_INIT_DIR"); dir != "" {
+		initGoals(filepath.Join(dir, "goal.yaml"), []string{"go"}, false)
+		return
+	}
+	dir := t.TempDir()
+	existing := "old: {cmd: echo}\n"
+	filename := filepath.Join(dir, "goal.yaml")
+	if err := ioutil.WriteFile(filename, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_initGoals_existingFile$")
+	cmd.Env = append(os.Environ(), "GOAL_TEST_INIT_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("initGoals() succeeded without --force: %s", out)
+	}
+	if want := "already exists, use --force to overwrite it"; !strings.Contains(string(out), want) {
+		t.Errorf("initGoals() output = %s, want %v", out, want)
+	}
+	if bytes, _ := ioutil.ReadFile(filename); string(bytes) != existing {
+		t.Errorf("initGoals() changed existing file to:\n%s", bytes)
+	}
+}
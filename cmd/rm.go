@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/aaabramov/goal/lib"
+	"github.com/spf13/cobra"
+)
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm GOAL [--on env]",
+	Short: "Remove goal from goal.yaml",
+	Long: `Removes goal (or only its env with --on) from goal.yaml keeping comments and formatting of the rest of the file.
+Goal is removed together with its last env.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeGoals,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := strings.TrimSpace(args[0])
+		file := editableGoalFile()
+		editGoalFile(file, func(content []byte) ([]byte, error) {
+			return lib.RemoveGoal(content, name, env)
+		})
+		if env != "" {
+			name += " on " + env
+		}
+		lib.Info("✅ Removed %s from %s", name, file)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+
+	rmCmd.Flags().StringVarP(&env, "on", "e", "", "Environment to remove goal from")
+	_ = rmCmd.RegisterFlagCompletionFunc("on", completeEnvs)
+}
@@ -1,30 +1,40 @@
 package cmd
 
 import (
+	"errors"
 	"github.com/aaabramov/goal/lib"
 	"github.com/spf13/cobra"
-	"io/ioutil"
+	"os"
+	"strings"
 )
 
 var goalFile string
+var mergeGoals bool
+var sortBy string
+var listAll bool
 var commands *lib.Goals
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "goal [goal to run]",
-	Short: "Define and safely run project scoped aliases",
-	Long:  `Allows you to create local aliases withing directory/repository with proper assertions upon executions.`,
-	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		res := make([]string, len(commands.Commands))
-		for _, command := range commands.Commands {
-			res = append(res, command.Name)
-		}
-		return res, cobra.ShellCompDirectiveNoFileComp
-	},
+	Use:               "goal [goal to run]",
+	Short:             "Define and safely run project scoped aliases",
+	Long:              `Allows you to create local aliases withing directory/repository with proper assertions upon executions.`,
+	ValidArgsFunction: completeGoals,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			loadGoals()
-			commands.Render()
+			switch sortBy {
+			case "":
+			case "name":
+				commands.SortByName()
+			default:
+				lib.Fatal("❗ Unknown --sort %s, expected 'name'", sortBy)
+			}
+			listed := commands
+			if !listAll {
+				listed = commands.WithoutHidden()
+			}
+			listed.Render()
 		}
 	},
 }
@@ -36,25 +46,117 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&goalFile, "config", "c", "goal.yaml", "goals file to use")
+	rootCmd.PersistentFlags().StringVarP(&goalFile, "config", "c", "", "goals file to use, goal.yaml in current or nearest parent directory by default")
+	rootCmd.PersistentFlags().BoolVarP(&mergeGoals, "merge", "m", false, "merge every goal.yaml up to repository root, nearer goals override farther ones")
+	rootCmd.Flags().StringVar(&sortBy, "sort", "", "list goals sorted by 'name' instead of declaration order")
+	rootCmd.Flags().BoolVarP(&listAll, "all", "a", false, "list hidden goals too")
 }
 
-func loadGoals() {
+// goalFiles returns goals files to load, nearest first, and the user-global goals file if it exists
+func goalFiles() ([]string, string) {
 	if goalFile != "" {
-		bytes, err := ioutil.ReadFile(goalFile)
-		if err != nil {
-			lib.Fatal("❗ Failed to read goals file: %s\n"+
-				"\t- check if goal.yaml files exists in current directory\n"+
-				"\t- specify goal.yaml explicitly using -c flag, e.g. 'goal -c ../goal.yaml'\n"+
-				"\t- run 'goal init' to generate example goal.yaml file in current directory", goalFile)
+		return []string{goalFile}, ""
+	}
+	files := lib.FindGoalFiles(".")
+	if !mergeGoals && len(files) > 1 {
+		files = files[:1]
+	}
+	var global string
+	if path := lib.GlobalGoalFile(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			global = path
 		}
-		parsed, err := lib.ParseCommands(bytes)
+	}
+	if len(files) == 0 && global == "" {
+		lib.Fatal("❗ No %s found in current directory or its parents up to repository root\n"+
+			"\t- specify goal.yaml explicitly using -c flag, e.g. 'goal -c ../goal.yaml'\n"+
+			"\t- run 'goal init' to generate example goal.yaml file in current directory", lib.GoalFileName)
+	}
+	return files, global
+}
+
+func loadGoals() {
+	files, global := goalFiles()
+	var loaded []*lib.Goals
+	for _, file := range files {
+		loaded = append(loaded, loadGoalFile(file, lib.LoadFile))
+	}
+	commands = lib.MergeGoals(loaded...)
+	if global != "" {
+		combined, err := lib.CombineGoals(commands, loadGoalFile(global, lib.LoadGlobalFile))
 		if err != nil {
-			lib.Fatal("❗ Invalid goals file: %s.\n", goalFile)
-		} else {
-			commands = parsed
+			lib.Fatal("❗ %s", err)
+		}
+		commands = combined
+	}
+}
+
+// completeGoals completes names and aliases of goals as the first argument
+func completeGoals(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 || !loadGoalsForCompletion() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var res []string
+	seen := map[string]bool{}
+	for _, command := range commands.WithoutHidden().Commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			if !seen[name] && strings.HasPrefix(name, toComplete) {
+				seen[name] = true
+				res = append(res, name)
+			}
 		}
-	} else {
-		lib.Fatal("❗ Goals filename not specified. Either create goal.yaml file or specify location explicitly with -c option")
 	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeEnvs completes environments of goal given as the first argument
+func completeEnvs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 || !loadGoalsForCompletion() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	name, err := commands.ResolveName(strings.TrimSpace(args[0]))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var res []string
+	for _, env := range commands.Envs(name) {
+		if env != "" && strings.HasPrefix(env, toComplete) {
+			res = append(res, env)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+// loadGoalsForCompletion loads goals unless there are none, so completion does not print errors
+func loadGoalsForCompletion() bool {
+	if commands != nil {
+		return true
+	}
+	if goalFile == "" && len(lib.FindGoalFiles(".")) == 0 {
+		return false
+	}
+	loadGoals()
+	return true
+}
+
+// loadGoalFile validates and loads existing file with load, lib.LoadGlobalFile for the user-global goals file
+func loadGoalFile(file string, load func(string) (*lib.Goals, error)) *lib.Goals {
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		lib.Fatal("❗ %s does not exist\n"+
+			"\t- check if goal.yaml files exists in current directory\n"+
+			"\t- specify goal.yaml explicitly using -c flag, e.g. 'goal -c ../goal.yaml'\n"+
+			"\t- run 'goal init' to generate example goal.yaml file in current directory", file)
+	}
+	if problems := lib.ValidateFile(file); len(problems) > 0 {
+		msg := "❗ Invalid goals file:"
+		for _, problem := range problems {
+			msg += "\n\t" + problem.String()
+		}
+		lib.Fatal("%s", msg)
+	}
+	goals, err := load(file)
+	if err != nil {
+		lib.Fatal("❗ %s", err)
+	}
+	return goals
 }
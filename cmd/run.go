@@ -13,14 +13,8 @@ var runCmd = &cobra.Command{
 	Use:   "run GOAL [--on env]",
 	Short: "Run specified goal",
 	//Long:  `TODO`,
-	Args: cobra.ExactArgs(1),
-	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		res := make([]string, len(commands.Commands))
-		for _, command := range commands.Commands {
-			res = append(res, command.Name)
-		}
-		return res, cobra.ShellCompDirectiveNoFileComp
-	},
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeGoals,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		loadGoals()
 	},
@@ -37,4 +31,5 @@ func init() {
 	rootCmd.AddCommand(runCmd)
 
 	runCmd.Flags().StringVarP(&env, "on", "e", "", "Environment to use, example: goal tf-apply --on dev")
+	_ = runCmd.RegisterFlagCompletionFunc("on", completeEnvs)
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aaabramov/goal/lib"
+
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print JSON Schema of goal.yaml",
+	Long: `Prints JSON Schema of goal.yaml for editor completion and validation, e.g. with yaml-language-server:
+
+# yaml-language-server: $schema=https://raw.githubusercontent.com/aaabramov/goal/master/goal.schema.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := lib.Schema()
+		if err != nil {
+			lib.Fatal("Failed to generate schema: %s", err)
+		}
+		fmt.Print(string(schema))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
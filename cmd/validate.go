@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/aaabramov/goal/lib"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [FILE...]",
+	Short: "Validate goals files",
+	Long: `Strictly validates goals files (and files they include) reporting every problem as file:line:column.
+Validates the files goal would load when FILE is omitted. Exits with 1 when any problem is found, e.g. for pre-commit hooks.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files := args
+		if len(files) == 0 {
+			var global string
+			files, global = goalFiles()
+			if global != "" {
+				files = append(files, global)
+			}
+		}
+		var problems []lib.Problem
+		for _, file := range files {
+			problems = append(problems, lib.ValidateFile(file)...)
+		}
+		for _, problem := range problems {
+			lib.Warn("❌ %s", problem)
+		}
+		if len(problems) > 0 {
+			lib.Fatal("❗ Found %d problem(s)", len(problems))
+		}
+		lib.Info("✅ %d file(s) are valid", len(files))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -1,16 +1,133 @@
 package lib
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/manifoldco/promptui"
+	"io/ioutil"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 type Assertion interface {
 	describe() string
-	check(commands Goals) error
+	check(e *evaluation) Result
+}
+
+// Status of an assertion check
+type Status int
+
+const (
+	// Pass means the precondition holds
+	Pass Status = iota
+	// Fail means the precondition was checked and does not hold
+	Fail
+	// Error means the precondition could not be checked, e.g. the CLI is not installed or not authenticated
+	Error
+	// Overridden means the precondition does not hold but run was allowed with a reason given in Message
+	Overridden
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	case Overridden:
+		return "OVERRIDDEN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Result of an assertion check. Expected and Actual are raw values, Err is set for Error status
+// and is a *ProbeError when a CLI could not be run.
+type Result struct {
+	Status   Status
+	Expected string
+	Actual   string
+	Message  string
+	Fix      string
+	Err      error
+}
+
+// ok tells whether goal may run, the precondition passed or was overridden
+func (r Result) ok() bool {
+	return r.Status == Pass || r.Status == Overridden
+}
+
+func passed() Result {
+	return Result{Status: Pass}
+}
+
+func failed(expected string, actual string, fix string) Result {
+	return Result{Status: Fail, Expected: expected, Actual: actual, Fix: fix}
+}
+
+func errored(err error) Result {
+	return Result{Status: Error, Err: err}
+}
+
+// compared passes when actual equals expected
+func compared(expected string, actual string, fix string) Result {
+	if actual == expected {
+		return Result{Status: Pass, Expected: expected, Actual: actual}
+	}
+	return failed(expected, actual, fix)
+}
+
+func (r Result) render(description string) string {
+	switch r.Status {
+	case Pass:
+		return "✅ Precondition: " + description
+	case Overridden:
+		return fmt.Sprintf("⚠️  Precondition overridden: %s\n\tReason:   %s", description, r.Message)
+	case Fail:
+		msg := "❌ Precondition failed: " + description
+		if r.Expected != "" || r.Actual != "" {
+			msg += fmt.Sprintf(
+				"\n"+
+					"\tExpected: %s\n"+
+					"\tActual:   %s",
+				displayValue(r.Expected),
+				displayValue(r.Actual),
+			)
+		}
+		if r.Message != "" {
+			msg += "\n\t" + strings.ReplaceAll(r.Message, "\n", "\n\t")
+		}
+		if r.Fix != "" {
+			msg += "\n\tFix:      " + r.Fix
+		}
+		return msg
+	default:
+		reason := "unknown error"
+		if r.Err != nil {
+			reason = strings.ReplaceAll(r.Err.Error(), "\n", "\n\t          ")
+		}
+		msg := fmt.Sprintf(
+			"⚠️  Precondition could not be checked: %s\n"+
+				"\tError:    %s",
+			description,
+			reason,
+		)
+		if r.Fix != "" {
+			msg += "\n\tFix:      " + r.Fix
+		}
+		return msg
+	}
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
 }
 
 var availableAssertions = []string{
@@ -19,6 +136,16 @@ var availableAssertions = []string{
 	"kubectl_context",
 	"gcloud_project",
 	"approval",
+	"env_var",
+	"file",
+	"tool_version",
+	"kubectl_namespace",
+	"kubectl_server",
+	"schedule",
+	"who",
+	"any_of",
+	"all_of",
+	"not",
 }
 
 // === CUSTOM
@@ -35,34 +162,21 @@ func (a RefAssertion) describe() string {
 	return a.Desc
 }
 
-func (a RefAssertion) check(c Goals) error {
+func (a RefAssertion) check(e *evaluation) Result {
 	// TODO: env or !env?
-	ref, exists := c.get(a.Ref)
-
-	if exists {
-		out := strings.TrimSpace(getOutput(ref.Name, ref.Args...))
-		if out != a.Expect {
-			msg := fmt.Sprintf(
-				"Precondition failed: %s\n"+
-					"\tOutput:   %s\n"+
-					"\tExpected: %s\n"+
-					"\tCLI:      %s",
-				ref.Name,
-				strconv.Quote(out),
-				strconv.Quote(a.Expect),
-				strconv.Quote(ref.Cli()),
-			)
-			if a.Fix != "" {
-				msg += fmt.Sprintf("\n\tFix: %s", a.Fix)
-			}
-			return errors.New(msg)
-		} else {
-			Info("✅ Precondition: " + a.Desc)
-			return nil
-		}
-	} else {
-		return errors.New("Unknown assertion ref: " + a.Ref)
+	ref, exists := e.goals.get(a.Ref)
+	if !exists {
+		return errored(errors.New("unknown assertion ref: " + a.Ref))
+	}
+	out, err := e.probe(ref.Cmd, ref.Args...)
+	if err != nil {
+		return errored(err)
+	}
+	res := compared(a.Expect, out, a.Fix)
+	if res.Status == Fail {
+		res.Message = "CLI:      " + ref.Cli()
 	}
+	return res
 }
 
 // === INTERNAL
@@ -74,7 +188,11 @@ func (a ApproveAssertion) describe() string {
 	return "Manual approval"
 }
 
-func (a ApproveAssertion) check(_ Goals) error {
+func (a ApproveAssertion) interactive() bool {
+	return true
+}
+
+func (a ApproveAssertion) check(_ *evaluation) Result {
 
 	prompt := promptui.Select{
 		Label: "Proceed?",
@@ -84,22 +202,185 @@ func (a ApproveAssertion) check(_ Goals) error {
 	_, result, err := prompt.Run()
 
 	if err != nil {
-		fmt.Printf("Prompt failed %v\n", err)
-		return err
+		return errored(fmt.Errorf("prompt failed: %s", err))
 	}
 
 	if result == "yes" {
 		Info("✅ Proceed approved.")
-		return nil
+		return passed()
 	} else {
-		return errors.New("❌ Proceed aborted")
+		res := failed("yes", result, "")
+		res.Message = "Proceed aborted"
+		return res
 	}
 }
 
+// === COMPOSITE
+
+// AnyOfAssertion passes when at least one of Assertions passes
+type AnyOfAssertion struct {
+	Assertions []Assertion
+}
+
+func (a AnyOfAssertion) describe() string {
+	return describeNested("any of:", a.Assertions)
+}
+
+func (a AnyOfAssertion) interactive() bool {
+	return anyInteractive(a.Assertions)
+}
+
+func (a AnyOfAssertion) check(e *evaluation) Result {
+	results := e.checkEach(a.Assertions)
+	status := Fail
+	for _, res := range results {
+		if res.ok() {
+			return res
+		}
+		if res.Status == Error {
+			status = Error
+		}
+	}
+	return compositeResult(status, "none of the branches passed", a.Assertions, results)
+}
+
+// AllOfAssertion passes when every one of Assertions passes
+type AllOfAssertion struct {
+	Assertions []Assertion
+}
+
+func (a AllOfAssertion) describe() string {
+	return describeNested("all of:", a.Assertions)
+}
+
+func (a AllOfAssertion) interactive() bool {
+	return anyInteractive(a.Assertions)
+}
+
+func (a AllOfAssertion) check(e *evaluation) Result {
+	results := e.checkEach(a.Assertions)
+	status := Pass
+	for _, res := range results {
+		if res.Status == Fail {
+			status = Fail
+			break
+		}
+		if res.Status == Error {
+			status = Error
+		}
+	}
+	if status == Pass {
+		var reasons []string
+		for _, res := range results {
+			if res.Status == Overridden {
+				reasons = append(reasons, res.Message)
+			}
+		}
+		if len(reasons) > 0 {
+			return Result{Status: Overridden, Message: strings.Join(reasons, "; ")}
+		}
+		return passed()
+	}
+	return compositeResult(status, "some of the branches did not pass", a.Assertions, results)
+}
+
+// NotAssertion passes when Assertion fails
+type NotAssertion struct {
+	Assertion Assertion
+}
+
+func (a NotAssertion) describe() string {
+	desc := a.Assertion.describe()
+	if strings.Contains(desc, "\n") {
+		return describeNested("not:", []Assertion{a.Assertion})
+	}
+	return "not " + desc
+}
+
+func (a NotAssertion) interactive() bool {
+	return isInteractive(a.Assertion)
+}
+
+func (a NotAssertion) check(e *evaluation) Result {
+	res := a.Assertion.check(e)
+	switch res.Status {
+	case Pass:
+		message := "negated assertion passed: " + firstLine(a.Assertion.describe())
+		if res.Actual != "" {
+			message += fmt.Sprintf(" (actual: %s)", res.Actual)
+		}
+		return Result{Status: Fail, Message: message}
+	case Fail:
+		return passed()
+	default:
+		return res
+	}
+}
+
+func anyInteractive(assertions []Assertion) bool {
+	for _, assert := range assertions {
+		if isInteractive(assert) {
+			return true
+		}
+	}
+	return false
+}
+
+// compositeResult explains which branches of a composite assertion did not pass
+func compositeResult(status Status, summary string, assertions []Assertion, results []Result) Result {
+	lines := []string{summary + ":"}
+	for i, res := range results {
+		lines = append(lines, "  "+indent(branchSummary(assertions[i], res), "    "))
+	}
+	report := strings.Join(lines, "\n")
+	if status == Error {
+		return errored(errors.New(report))
+	}
+	return Result{Status: Fail, Message: report}
+}
+
+func branchSummary(assert Assertion, res Result) string {
+	desc := firstLine(assert.describe())
+	switch res.Status {
+	case Pass:
+		return "✅ " + desc
+	case Overridden:
+		return fmt.Sprintf("⚠️  %s: overridden, %s", desc, res.Message)
+	case Fail:
+		summary := "❌ " + desc
+		if res.Expected != "" || res.Actual != "" {
+			summary += fmt.Sprintf(": expected %s, actual %s", displayValue(res.Expected), displayValue(res.Actual))
+		}
+		if res.Message != "" {
+			summary += "\n" + res.Message
+		}
+		return summary
+	default:
+		return fmt.Sprintf("⚠️  %s: %s", desc, res.Err)
+	}
+}
+
+func describeNested(title string, assertions []Assertion) string {
+	lines := []string{title}
+	for _, assert := range assertions {
+		lines = append(lines, "  - "+indent(assert.describe(), "    "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indent prefixes every line but the first one
+func indent(text string, prefix string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func firstLine(text string) string {
+	return strings.SplitN(text, "\n", 2)[0]
+}
+
 // === TERRAFORM
 
-// TerraformWorkspaceAssertion checks current Terraform workspace by executing `terraform workspace show`
-// and compares its output with Expect
+// TerraformWorkspaceAssertion checks current Terraform workspace (TF_WORKSPACE or .terraform/environment,
+// `terraform workspace show` when neither is present) and compares it with Expect
 type TerraformWorkspaceAssertion struct {
 	Expect string
 }
@@ -108,30 +389,18 @@ func (a TerraformWorkspaceAssertion) describe() string {
 	return fmt.Sprintf("terraform.workspace == %s", strconv.Quote(a.Expect))
 }
 
-func (a TerraformWorkspaceAssertion) check(_ Goals) error {
-	out := strings.TrimSpace(getOutput("terraform", "workspace", "show"))
-	if out == a.Expect {
-		return nil
-	} else {
-		return errors.New(
-			fmt.Sprintf(
-				"❌ Precondition failed: %s\n"+
-					"\tExpected terraform workspace to be: %s\n"+
-					"\tActual terraform workspace:         %s\n"+
-					"\tFix:                                \"terraform workspace select %s\"",
-				a.describe(),
-				strconv.Quote(out),
-				strconv.Quote(a.Expect),
-				a.Expect,
-			),
-		)
+func (a TerraformWorkspaceAssertion) check(e *evaluation) Result {
+	out, err := currentTerraformWorkspace(e)
+	if err != nil {
+		return errored(err)
 	}
+	return compared(a.Expect, out, "terraform workspace select "+a.Expect)
 }
 
 // === KUBERNETES
 
-// KubectlContextAssertion checks current `kubectl` context by executing `kubectl config current-context`
-// and compares its output with Expect
+// KubectlContextAssertion checks current `kubectl` context from kubeconfig files
+// (`kubectl config current-context` when there are none) and compares it with Expect
 type KubectlContextAssertion struct {
 	Expect string
 }
@@ -140,30 +409,80 @@ func (a KubectlContextAssertion) describe() string {
 	return fmt.Sprintf("kubectl.context == %s", strconv.Quote(a.Expect))
 }
 
-func (a KubectlContextAssertion) check(_ Goals) error {
-	out := strings.TrimSpace(getOutput("kubectl", "config", "current-context"))
-	if out == a.Expect {
-		return nil
-	} else {
-		return errors.New(
-			fmt.Sprintf(
-				"❌ Precondition failed: %s\n"+
-					"\tExpected kubectl context to be: %s\n"+
-					"\tActual kubectl context:         %s\n"+
-					"\tFix:                            \"kubectl config use-context %s\"",
-				a.describe(),
-				strconv.Quote(out),
-				strconv.Quote(a.Expect),
-				a.Expect,
-			),
-		)
+func (a KubectlContextAssertion) check(e *evaluation) Result {
+	out, err := currentKubeContext(e)
+	if err != nil {
+		return errored(err)
+	}
+	return compared(a.Expect, out, "kubectl config use-context "+a.Expect)
+}
+
+// KubectlNamespaceAssertion checks namespace of the current `kubectl` context, "default" when it is not set
+type KubectlNamespaceAssertion struct {
+	Expect string
+}
+
+func (a KubectlNamespaceAssertion) describe() string {
+	return fmt.Sprintf("kubectl.namespace == %s", strconv.Quote(a.Expect))
+}
+
+func (a KubectlNamespaceAssertion) check(e *evaluation) Result {
+	out, err := currentKubeNamespace(e)
+	if err != nil {
+		return errored(err)
 	}
+	return compared(a.Expect, out, "kubectl config set-context --current --namespace="+a.Expect)
+}
+
+// KubectlServerAssertion checks cluster of the current `kubectl` context by its API Server URL and/or
+// SHA-256 fingerprint of its certificate authority. Unlike context names these are the same on every machine.
+type KubectlServerAssertion struct {
+	Server   string
+	CaSha256 string
+}
+
+func (a KubectlServerAssertion) describe() string {
+	var checks []string
+	if a.Server != "" {
+		checks = append(checks, fmt.Sprintf("kubectl.server == %s", strconv.Quote(a.Server)))
+	}
+	if a.CaSha256 != "" {
+		checks = append(checks, fmt.Sprintf("kubectl.ca_sha256 == %s", strconv.Quote(a.CaSha256)))
+	}
+	return strings.Join(checks, " && ")
+}
+
+func (a KubectlServerAssertion) check(e *evaluation) Result {
+	const fix = "kubectl config use-context <context of this cluster>"
+	if a.Server != "" {
+		out, err := currentKubeServer(e)
+		if err != nil {
+			return errored(err)
+		}
+		if strings.TrimSuffix(out, "/") != strings.TrimSuffix(a.Server, "/") {
+			res := failed(a.Server, out, fix)
+			res.Message = "API server of the current context differs"
+			return res
+		}
+	}
+	if a.CaSha256 != "" {
+		out, err := currentKubeCaFingerprint(e)
+		if err != nil {
+			return errored(err)
+		}
+		if out != normalizeFingerprint(a.CaSha256) {
+			res := failed(normalizeFingerprint(a.CaSha256), out, fix)
+			res.Message = "CA fingerprint of the current context's cluster differs"
+			return res
+		}
+	}
+	return passed()
 }
 
 // === GCP
 
-// GcloudProjectAssertion checks current `gcloud` project by executing `gcloud config get-value project`
-// and compares its output with Expect
+// GcloudProjectAssertion checks current `gcloud` project from the active gcloud configuration
+// (`gcloud config get-value project` when there is none) and compares it with Expect
 type GcloudProjectAssertion struct {
 	Expect string
 }
@@ -172,22 +491,167 @@ func (a GcloudProjectAssertion) describe() string {
 	return fmt.Sprintf("gcloud.project == %s", strconv.Quote(a.Expect))
 }
 
-func (a GcloudProjectAssertion) check(_ Goals) error {
-	out := strings.TrimSpace(getOutput("gcloud", "config", "get-value", "project"))
-	if out == a.Expect {
-		return nil
-	} else {
-		return errors.New(
-			fmt.Sprintf(
-				"❌ Precondition failed: %s\n"+
-					"\tExpected gcloud project to be: %s\n"+
-					"\tActual gcloud project:         %s\n"+
-					"\tFix:                           \"gcloud config set project %s\"",
-				a.describe(),
-				strconv.Quote(out),
-				strconv.Quote(a.Expect),
-				a.Expect,
-			),
-		)
+func (a GcloudProjectAssertion) check(e *evaluation) Result {
+	out, err := currentGcloudProject(e)
+	if err != nil {
+		return errored(err)
+	}
+	return compared(a.Expect, out, "gcloud config set project "+a.Expect)
+}
+
+// === TOOLS
+
+// ToolVersionAssertion runs version command of Tool, parses the semantic version from its output
+// and checks it against Constraint, e.g. ">= 1.3, < 2"
+type ToolVersionAssertion struct {
+	Tool         string
+	Constraint   string
+	VersionCmd   string
+	VersionRegex string
+	Fix          string
+}
+
+func (a ToolVersionAssertion) describe() string {
+	return fmt.Sprintf("%s.version %s", a.Tool, a.Constraint)
+}
+
+func (a ToolVersionAssertion) check(e *evaluation) Result {
+	constraint, err := parseVersionConstraint(a.Constraint)
+	if err != nil {
+		return errored(err)
+	}
+	name, args, extract := versionProbe(a.Tool, a.VersionCmd, a.VersionRegex)
+	out, err := e.probe(name, args...)
+	if err != nil {
+		return errored(err)
+	}
+	raw, err := extract(out)
+	if err != nil {
+		return errored(fmt.Errorf("could not determine %s version: %s", a.Tool, err))
+	}
+	version, err := parseSemver(raw)
+	if err != nil {
+		return errored(err)
+	}
+	if constraint.matches(version) {
+		return Result{Status: Pass, Expected: a.Constraint, Actual: raw}
+	}
+	return failed(a.Constraint, raw, a.Fix)
+}
+
+// === ENVIRONMENT
+
+// EnvVarAssertion checks environment variable Name of the current process.
+// Depending on what is configured the value must be equal to Expect, match ExpectRegex or just be set.
+type EnvVarAssertion struct {
+	Name        string
+	Expect      string
+	ExpectRegex string
+	Fix         string
+}
+
+func (a EnvVarAssertion) describe() string {
+	if a.Expect != "" {
+		return fmt.Sprintf("env.%s == %s", a.Name, strconv.Quote(a.Expect))
+	}
+	if a.ExpectRegex != "" {
+		return fmt.Sprintf("env.%s =~ %s", a.Name, strconv.Quote(a.ExpectRegex))
+	}
+	return fmt.Sprintf("env.%s is set", a.Name)
+}
+
+func (a EnvVarAssertion) check(_ *evaluation) Result {
+	value, set := os.LookupEnv(a.Name)
+	fix := a.Fix
+	var res Result
+	switch {
+	case a.Expect != "":
+		if fix == "" {
+			fix = fmt.Sprintf("export %s=%s", a.Name, shellQuote(a.Expect))
+		}
+		res = compared(a.Expect, value, fix)
+	case a.ExpectRegex != "":
+		re, err := regexp.Compile(a.ExpectRegex)
+		if err != nil {
+			return errored(fmt.Errorf("invalid expect_regex: %s", err))
+		}
+		res = failed("=~ "+a.ExpectRegex, value, fix)
+		if set && re.MatchString(value) {
+			res.Status = Pass
+		}
+	default:
+		res = failed("non-empty value", value, fix)
+		if value != "" {
+			res.Status = Pass
+		}
+	}
+	if !set && res.Status == Fail {
+		res.Message = a.Name + " is not set"
+	}
+	return res
+}
+
+// === FILES
+
+// FileAssertion checks file at Path relative to current directory. By default the file must exist,
+// set Exists to false to require it to be absent. Contains and Sha256 additionally check file content.
+type FileAssertion struct {
+	Path     string
+	Exists   bool
+	Contains string
+	Sha256   string
+	Fix      string
+}
+
+func (a FileAssertion) describe() string {
+	if !a.Exists {
+		return fmt.Sprintf("file %s does not exist", strconv.Quote(a.Path))
+	}
+	checks := []string{"exists"}
+	if a.Contains != "" {
+		checks = append(checks, "contains "+strconv.Quote(a.Contains))
+	}
+	if a.Sha256 != "" {
+		checks = append(checks, "sha256 == "+strconv.Quote(a.Sha256))
+	}
+	return fmt.Sprintf("file %s %s", strconv.Quote(a.Path), strings.Join(checks, ", "))
+}
+
+func (a FileAssertion) check(_ *evaluation) Result {
+	content, err := ioutil.ReadFile(a.Path)
+	exists := !os.IsNotExist(err)
+	if !a.Exists {
+		if exists {
+			return failed("absent", "exists", a.Fix)
+		}
+		return passed()
+	}
+	if !exists {
+		return failed("exists", "absent", a.Fix)
+	}
+	if err != nil {
+		return errored(err)
+	}
+	if a.Contains != "" && !strings.Contains(string(content), a.Contains) {
+		res := failed(a.Contains, truncate(string(content), 80), a.Fix)
+		res.Message = "file content does not contain expected text"
+		return res
+	}
+	if a.Sha256 != "" {
+		sum := sha256.Sum256(content)
+		actual := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(actual, a.Sha256) {
+			res := failed(a.Sha256, actual, a.Fix)
+			res.Message = "sha256 of file content differs"
+			return res
+		}
+	}
+	return passed()
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
 	}
+	return s[:max] + "..."
 }
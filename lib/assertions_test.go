@@ -0,0 +1,214 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvVarAssertion_check(t *testing.T) {
+	t.Setenv("GOAL_TEST_KUBECONFIG", "/home/user/.kube/stage")
+	t.Setenv("GOAL_TEST_EMPTY", "")
+
+	tests := []struct {
+		name   string
+		assert EnvVarAssertion
+		want   Status
+	}{
+		{name: "expect matches", assert: EnvVarAssertion{Name: "GOAL_TEST_KUBECONFIG", Expect: "/home/user/.kube/stage"}},
+		{name: "expect differs", assert: EnvVarAssertion{Name: "GOAL_TEST_KUBECONFIG", Expect: "/home/user/.kube/prod"}, want: Fail},
+		{name: "regex matches", assert: EnvVarAssertion{Name: "GOAL_TEST_KUBECONFIG", ExpectRegex: `stage$`}},
+		{name: "regex differs", assert: EnvVarAssertion{Name: "GOAL_TEST_KUBECONFIG", ExpectRegex: `prod$`}, want: Fail},
+		{name: "regex on unset variable", assert: EnvVarAssertion{Name: "GOAL_TEST_UNSET", ExpectRegex: `.*`}, want: Fail},
+		{name: "set", assert: EnvVarAssertion{Name: "GOAL_TEST_KUBECONFIG"}},
+		{name: "set but empty", assert: EnvVarAssertion{Name: "GOAL_TEST_EMPTY"}, want: Fail},
+		{name: "not set", assert: EnvVarAssertion{Name: "GOAL_TEST_UNSET"}, want: Fail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.assert.check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvVarAssertion_check_fix(t *testing.T) {
+	t.Setenv("GOAL_TEST_CLUSTER", "")
+
+	tests := []struct {
+		expect string
+		want   string
+	}{
+		{expect: "stage", want: "export GOAL_TEST_CLUSTER=stage"},
+		{expect: "my cluster", want: "export GOAL_TEST_CLUSTER='my cluster'"},
+		{expect: "it's", want: `export GOAL_TEST_CLUSTER='it'\''s'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expect, func(t *testing.T) {
+			got := EnvVarAssertion{Name: "GOAL_TEST_CLUSTER", Expect: tt.expect}.check(newEvaluation(Goals{}))
+			if got.Fix != tt.want {
+				t.Errorf("check().Fix = %v, want %v", got.Fix, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileAssertion_check(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "environment")
+	if err := ioutil.WriteFile(path, []byte("stage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	// sha256 of "stage"
+	sum := "c7ff6dcd94d7161eff5da0585684a8d16fb00090c0f38336d31950819e2f2003"
+
+	tests := []struct {
+		name   string
+		assert FileAssertion
+		want   Status
+	}{
+		{name: "exists", assert: FileAssertion{Path: path, Exists: true}},
+		{name: "missing", assert: FileAssertion{Path: missing, Exists: true}, want: Fail},
+		{name: "absent", assert: FileAssertion{Path: missing, Exists: false}},
+		{name: "not absent", assert: FileAssertion{Path: path, Exists: false}, want: Fail},
+		{name: "contains", assert: FileAssertion{Path: path, Exists: true, Contains: "stage"}},
+		{name: "does not contain", assert: FileAssertion{Path: path, Exists: true, Contains: "prod"}, want: Fail},
+		{name: "sha256 matches", assert: FileAssertion{Path: path, Exists: true, Sha256: sum}},
+		{name: "sha256 differs", assert: FileAssertion{Path: path, Exists: true, Sha256: "deadbeef"}, want: Fail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.assert.check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_caFingerprint(t *testing.T) {
+	der := []byte("not really a certificate, but fingerprint only hashes DER bytes")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	sum := sha256.Sum256(der)
+	want := hex.EncodeToString(sum[:])
+
+	got, err := caFingerprint(caPem)
+	if err != nil {
+		t.Fatalf("caFingerprint() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("caFingerprint() = %v, want %v", got, want)
+	}
+	if colons := strings.ToUpper(strings.Join(splitPairs(want), ":")); normalizeFingerprint(colons) != want {
+		t.Errorf("normalizeFingerprint(%v) = %v, want %v", colons, normalizeFingerprint(colons), want)
+	}
+	if _, err := caFingerprint([]byte("garbage")); err == nil {
+		t.Errorf("caFingerprint() expected error for non-PEM input")
+	}
+}
+
+func splitPairs(s string) (pairs []string) {
+	for i := 0; i < len(s); i += 2 {
+		pairs = append(pairs, s[i:i+2])
+	}
+	return
+}
+
+func TestTerraformWorkspaceAssertion_check(t *testing.T) {
+	dataDir := t.TempDir()
+	writeFile(t, filepath.Join(dataDir, "environment"), "stage\n")
+	t.Setenv("TF_DATA_DIR", dataDir)
+
+	tests := []struct {
+		name        string
+		tfWorkspace string
+		expect      string
+		want        Status
+	}{
+		{name: "from data dir", expect: "stage"},
+		{name: "wrong workspace", expect: "prod", want: Fail},
+		{name: "TF_WORKSPACE wins", tfWorkspace: "prod", expect: "prod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TF_WORKSPACE", tt.tfWorkspace)
+			if got := (TerraformWorkspaceAssertion{Expect: tt.expect}).check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositeAssertions_check(t *testing.T) {
+	t.Setenv("GOAL_TEST_CONTEXT", "stage-us")
+	eu := EnvVarAssertion{Name: "GOAL_TEST_CONTEXT", Expect: "stage-eu"}
+	us := EnvVarAssertion{Name: "GOAL_TEST_CONTEXT", Expect: "stage-us"}
+	broken := EnvVarAssertion{Name: "GOAL_TEST_CONTEXT", ExpectRegex: "("}
+
+	tests := []struct {
+		name   string
+		assert Assertion
+		want   Status
+	}{
+		{name: "any of, one passes", assert: AnyOfAssertion{Assertions: []Assertion{eu, us}}, want: Pass},
+		{name: "any of, none passes", assert: AnyOfAssertion{Assertions: []Assertion{eu, eu}}, want: Fail},
+		{name: "any of, error wins over fail", assert: AnyOfAssertion{Assertions: []Assertion{eu, broken}}, want: Error},
+		{name: "any of, pass wins over error", assert: AnyOfAssertion{Assertions: []Assertion{broken, us}}, want: Pass},
+		{name: "all of, all pass", assert: AllOfAssertion{Assertions: []Assertion{us, us}}, want: Pass},
+		{name: "all of, one fails", assert: AllOfAssertion{Assertions: []Assertion{us, eu}}, want: Fail},
+		{name: "all of, fail wins over error", assert: AllOfAssertion{Assertions: []Assertion{broken, eu}}, want: Fail},
+		{name: "not, negates pass", assert: NotAssertion{Assertion: us}, want: Fail},
+		{name: "not, negates fail", assert: NotAssertion{Assertion: eu}, want: Pass},
+		{name: "not, keeps error", assert: NotAssertion{Assertion: broken}, want: Error},
+		{
+			name:   "nested",
+			assert: AllOfAssertion{Assertions: []Assertion{NotAssertion{Assertion: eu}, AnyOfAssertion{Assertions: []Assertion{eu, us}}}},
+			want:   Pass,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.assert.check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositeAssertions_describe(t *testing.T) {
+	assert := AnyOfAssertion{Assertions: []Assertion{
+		KubectlContextAssertion{Expect: "stage-eu"},
+		AllOfAssertion{Assertions: []Assertion{
+			KubectlContextAssertion{Expect: "stage-us"},
+			NotAssertion{Assertion: GcloudProjectAssertion{Expect: "prod"}},
+		}},
+	}}
+	want := `any of:
+  - kubectl.context == "stage-eu"
+  - all of:
+      - kubectl.context == "stage-us"
+      - not gcloud.project == "prod"`
+	if got := assert.describe(); got != want {
+		t.Errorf("describe() = \n%v\nwant\n%v", got, want)
+	}
+
+	t.Setenv("GOAL_TEST_CONTEXT", "dev")
+	failure := AnyOfAssertion{Assertions: []Assertion{
+		EnvVarAssertion{Name: "GOAL_TEST_CONTEXT", Expect: "stage-eu"},
+		EnvVarAssertion{Name: "GOAL_TEST_CONTEXT", Expect: "stage-us"},
+	}}
+	rendered := failure.check(newEvaluation(Goals{})).render(failure.describe())
+	for _, branch := range []string{
+		`❌ env.GOAL_TEST_CONTEXT == "stage-eu": expected stage-eu, actual dev`,
+		`❌ env.GOAL_TEST_CONTEXT == "stage-us": expected stage-us, actual dev`,
+	} {
+		if !strings.Contains(rendered, branch) {
+			t.Errorf("render() = \n%v\nwant to contain %v", rendered, branch)
+		}
+	}
+}
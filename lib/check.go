@@ -0,0 +1,136 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// skipped is reported for approval, which is never asked by `goal check`
+const skipped = "SKIP"
+
+// CheckRow is the outcome of a single assertion of a goal
+type CheckRow struct {
+	Goal      string `json:"goal"`
+	Env       string `json:"env,omitempty"`
+	Assertion string `json:"assertion"`
+	Status    string `json:"status"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Fix       string `json:"fix,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CheckReport holds outcomes of assertions in declaration order
+type CheckReport struct {
+	Rows []CheckRow `json:"results"`
+}
+
+// Select returns goals named name on env. Empty env selects every environment of the goal, empty name every goal.
+func (c *Goals) Select(name string, env string) []Goal {
+	var selected []Goal
+	for _, goal := range c.Commands {
+		if (name == "" || goal.Name == name) && (env == "" || goal.Env == env) {
+			selected = append(selected, goal)
+		}
+	}
+	return selected
+}
+
+// Check evaluates assertions of goals but approval without running them. User is never prompted,
+// e.g. a blocked schedule is overridden only by GOAL_OVERRIDE_REASON and reported as such.
+func (c *Goals) Check(goals []Goal) CheckReport {
+	var report CheckReport
+	for i := range goals {
+		goal := &goals[i]
+		asserts := c.assertions(*goal)
+		var automatic []Assertion
+		for _, assert := range asserts {
+			if !isApproval(assert) {
+				automatic = append(automatic, assert)
+			}
+		}
+		e := newEvaluation(*c)
+		e.goal = goal
+		var results []Result
+		inDir(goal.Dir, func() {
+			results = e.checkConcurrently(automatic)
+		})
+		next := 0
+		for _, assert := range asserts {
+			row := CheckRow{Goal: goal.Name, Env: goal.Env, Assertion: assert.describe(), Status: skipped}
+			if !isApproval(assert) {
+				res := results[next]
+				next++
+				row.Status = res.Status.String()
+				row.Expected, row.Actual, row.Message, row.Fix = res.Expected, res.Actual, res.Message, res.Fix
+				if res.Err != nil {
+					row.Error = res.Err.Error()
+				}
+			}
+			report.Rows = append(report.Rows, row)
+		}
+	}
+	return report
+}
+
+// isApproval tells whether assert asks user for approval, it could not be nested
+func isApproval(assert Assertion) bool {
+	_, approval := assert.(ApproveAssertion)
+	return approval
+}
+
+// ExitCode is 0 when every checked assertion passed, 1 when some failed or were overridden and 2 when some could not be checked
+func (r CheckReport) ExitCode() int {
+	code := 0
+	for _, row := range r.Rows {
+		switch row.Status {
+		case Fail.String(), Overridden.String():
+			return 1
+		case Error.String():
+			code = 2
+		}
+	}
+	return code
+}
+
+func (r CheckReport) RenderJson() {
+	renderJson(r)
+}
+
+func renderJson(report interface{}) {
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		Fatal("❗ Failed to render report: %s", err)
+	}
+	// not Info, which would format values of the report, e.g. `100%`
+	fmt.Println(string(out))
+}
+
+func (r CheckReport) Render() {
+	table := newTable("goal", "Environment", "Assertion", "Status", "Actual", "Fix")
+	for _, row := range r.Rows {
+		actual := row.Actual
+		if row.Error != "" {
+			actual = row.Error
+		} else if row.Message != "" {
+			actual = strings.TrimSpace(actual + "\n" + row.Message)
+		}
+		table.Append([]string{row.Goal, row.Env, row.Assertion, statusIcon(row.Status) + " " + row.Status, actual, row.Fix})
+	}
+	table.Render()
+}
+
+func statusIcon(status string) string {
+	switch status {
+	case Pass.String():
+		return "✅"
+	case Fail.String():
+		return "❌"
+	case Error.String(), Overridden.String():
+		return "⚠️ "
+	default:
+		return "⏭ "
+	}
+}
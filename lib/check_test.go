@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGoals_Check(t *testing.T) {
+	t.Setenv("GOAL_TEST_REGION", "eu")
+	goals := Goals{Commands: []Goal{
+		{Name: "apply", Env: "eu", Assert: []Assertion{
+			EnvVarAssertion{Name: "GOAL_TEST_REGION", Expect: "eu"},
+			ApproveAssertion{},
+		}},
+		{Name: "apply", Env: "us", Assert: []Assertion{
+			EnvVarAssertion{Name: "GOAL_TEST_REGION", Expect: "us", Fix: "export GOAL_TEST_REGION=us"},
+		}},
+	}}
+
+	tests := []struct {
+		name     string
+		goal     string
+		env      string
+		want     []string
+		wantCode int
+	}{
+		{name: "single env", goal: "apply", env: "eu", want: []string{"PASS", "SKIP"}, wantCode: 0},
+		{name: "every env of goal", goal: "apply", want: []string{"PASS", "SKIP", "FAIL"}, wantCode: 1},
+		{name: "unknown", goal: "destroy", want: nil, wantCode: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := goals.Check(goals.Select(tt.goal, tt.env))
+			var got []string
+			for _, row := range report.Rows {
+				got = append(got, row.Status)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Check() = %v, want %v", got, tt.want)
+				}
+			}
+			if code := report.ExitCode(); code != tt.wantCode {
+				t.Errorf("ExitCode() = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+
+	report := goals.Check(goals.Select("apply", "us"))
+	if row := report.Rows[0]; row.Actual != "eu" || row.Fix != "export GOAL_TEST_REGION=us" {
+		t.Errorf("Check() = %+v, want actual value and fix", row)
+	}
+}
+
+func TestCheckReport_ExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     int
+	}{
+		{name: "passed", statuses: []string{"PASS", "SKIP"}, want: 0},
+		{name: "not checked", statuses: []string{"PASS", "ERROR"}, want: 2},
+		{name: "failed", statuses: []string{"ERROR", "FAIL"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report CheckReport
+			for _, status := range tt.statuses {
+				report.Rows = append(report.Rows, CheckRow{Status: status})
+			}
+			if got := report.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckReport_RenderJson(t *testing.T) {
+	report := CheckReport{Rows: []CheckRow{{Goal: "deploy", Assertion: "env.GOAL_PCT == \"100%\"", Status: "FAIL", Actual: "50%", Fix: "export GOAL_PCT=100%"}}}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	report.RenderJson()
+	os.Stdout = stdout
+	_ = w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got CheckReport
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("RenderJson() = %s, invalid JSON: %s", out, err)
+	}
+	if len(got.Rows) != 1 || got.Rows[0] != report.Rows[0] {
+		t.Errorf("RenderJson() = %+v, want %+v", got, report)
+	}
+}
@@ -1,12 +1,12 @@
 package lib
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
 	"gopkg.in/yaml.v2"
 	"os"
 	osexec "os/exec"
+	"regexp"
 	"sort"
 	"strings"
 )
@@ -18,14 +18,39 @@ type Goal struct {
 	Assert []Assertion
 	Env    string
 	Desc   string
+	// Dir is the working directory of goal, relative to Source unless absolute. Current directory when empty.
+	Dir string
+	// Source is the goals file defining goal, empty when parsed from bytes
+	Source string
+	// Group is a section goal is listed in, e.g. Terraform
+	Group string
+	// Aliases are alternative names of goal, e.g. tfa
+	Aliases []string
+	// Hidden goals, e.g. probes used by ref assertions, are not listed unless asked for
+	Hidden bool
 }
 
+// Cli is the command line of goal which could be pasted to shell, args are quoted when needed
 func (c Goal) Cli() string {
 	if len(c.Args) == 0 {
 		return c.Cmd
-	} else {
-		return fmt.Sprintf("%s %s", c.Cmd, strings.Join(c.Args, " "))
 	}
+	quoted := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		quoted[i] = shellQuote(arg)
+	}
+	return fmt.Sprintf("%s %s", c.Cmd, strings.Join(quoted, " "))
+}
+
+// shellSafe matches words passed by shell as they are
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for POSIX shell unless it is a safe word
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func (c Goal) String() string {
@@ -33,7 +58,8 @@ func (c Goal) String() string {
 }
 
 type Goals struct {
-	Commands []Goal
+	Commands     []Goal
+	Environments []Environment
 }
 
 func (c *Goals) get(name string) (*Goal, bool) {
@@ -60,20 +86,20 @@ func (c *Goals) GetWithEnv(name string, env string) (*Goal, bool) {
 
 func (c *Goals) Exec(name string, env string) {
 
-	command, exists := c.GetWithEnv(name, env)
-	if exists {
+	command, err := c.Resolve(name, env)
+	if err == nil {
+		env = command.Env
 		msg := fmt.Sprintf("🔨 Exec %s", command.Name)
 		if env != "" {
-			msg += " on " + env
+			msg += " on " + c.colored(env, env)
 		}
-		Info(msg)
-		for _, assert := range command.Assert {
-			Info("⌛ Check precondition: %s", assert.describe())
-			if err := assert.check(*c); err != nil {
-				Fatal(err.Error())
+		Info("%s", msg)
+		if command.Dir != "" {
+			if err := os.Chdir(command.Dir); err != nil {
+				Fatal("❗ Failed to enter goal directory %s: %s", command.Dir, err)
 			}
-			Info("✅ Precondition: %s", assert.describe())
 		}
+		c.checkPreconditions(command)
 
 		cmd := osexec.Command(command.Cmd, command.Args...)
 		cmd.Stdout = os.Stdout
@@ -82,59 +108,153 @@ func (c *Goals) Exec(name string, env string) {
 		err := cmd.Run()
 
 		if err != nil {
-			Info(fmt.Sprint(err))
+			Info("%s", err)
 			// TODO: code from child program
 			os.Exit(1)
 		} else {
 			os.Exit(0)
 		}
 	} else {
-		Fatal("❗ No such command in goal.yaml: %s", name)
+		Fatal("❗ %s", err)
 	}
 
 }
 
-func (c *Goals) Render() {
-	Info("Available goals:")
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"goal", "Environment", "CLI", "Description", "Assertions"})
-	table.SetRowLine(true)
-	table.SetAutoMergeCells(true)
-	table.SetAutoWrapText(false)
-	table.SetHeaderColor(
-		tablewriter.Colors{tablewriter.Bold},
-		tablewriter.Colors{tablewriter.Bold},
-		tablewriter.Colors{tablewriter.Bold},
-		tablewriter.Colors{tablewriter.Bold},
-		tablewriter.Colors{tablewriter.Bold},
-	)
-	table.SetColumnColor(
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-		tablewriter.Colors{tablewriter.Normal},
-		tablewriter.Colors{tablewriter.Normal},
-		tablewriter.Colors{tablewriter.Normal},
-		tablewriter.Colors{tablewriter.Normal},
-	)
-	for _, cmd := range c.Commands {
-		var assertions []string
+// checkPreconditions checks non-interactive assertions of goal concurrently and reports them in declaration order.
+// Interactive ones (e.g. approval) are checked after that one by one, only when all others passed.
+func (c *Goals) checkPreconditions(goal *Goal) {
+	var automatic, interactive []Assertion
+	for _, assert := range c.assertions(*goal) {
+		if isInteractive(assert) {
+			interactive = append(interactive, assert)
+		} else {
+			automatic = append(automatic, assert)
+		}
+	}
+	e := newEvaluation(*c)
+	e.goal = goal
+	e.prompt = true
+	if len(automatic) > 0 {
+		Info("⌛ Check %d precondition(s)", len(automatic))
+	}
+	failures := 0
+	for i, res := range e.checkConcurrently(automatic) {
+		switch {
+		case res.Status == Pass:
+			Info("%s", res.render(automatic[i].describe()))
+		case res.ok():
+			Warn("%s", res.render(automatic[i].describe()))
+		default:
+			failures++
+			Warn("%s", res.render(automatic[i].describe()))
+		}
+	}
+	if failures > 0 {
+		Fatal("❗ %d of %d precondition(s) did not pass", failures, len(automatic))
+	}
+	for _, assert := range interactive {
+		Info("⌛ Check precondition: %s", assert.describe())
+		res := assert.check(e)
+		if !res.ok() {
+			Fatal("%s", res.render(assert.describe()))
+		}
+		if res.Status == Overridden {
+			Warn("%s", res.render(assert.describe()))
+		} else {
+			Info("%s", res.render(assert.describe()))
+		}
+	}
+}
 
-		for idx, assert := range cmd.Assert {
-			assertions = append(assertions, fmt.Sprintf("%d. %s", idx+1, assert.describe()))
+// sources returns distinct files goals were defined in
+func (c *Goals) sources() []string {
+	var sources []string
+	seen := map[string]bool{}
+	for _, goal := range c.Commands {
+		if !seen[goal.Source] {
+			seen[goal.Source] = true
+			sources = append(sources, goal.Source)
 		}
-		table.Append([]string{cmd.Name, cmd.Env, cmd.Cli(), cmd.Desc, strings.Join(assertions, "\n")})
 	}
-	table.Render()
+	return sources
 }
 
-func getOutput(name string, args ...string) string {
-	cmd := osexec.Command(name, args...)
-	var output bytes.Buffer
-	cmd.Stdout = &output
+// ungrouped is a section of goals without group when other goals have one
+const ungrouped = "Other"
+
+func (c *Goals) Render() {
+	sources := c.sources()
+	header := []string{"goal", "Environment", "CLI", "Description", "Assertions"}
+	switch {
+	case len(sources) == 1 && sources[0] != "":
+		Info("Available goals in %s:", sources[0])
+	case len(sources) > 1:
+		Info("Available goals:")
+		header = append(header, "Source")
+	default:
+		Info("Available goals:")
+	}
+	groups, grouped := c.groups()
+	for _, group := range groups {
+		if len(groups) > 1 || group != ungrouped {
+			Info("\n%s", group)
+		}
+		table := newTable(header...)
+		for _, cmd := range grouped[group] {
+			var assertions []string
 
-	// TODO: handle
-	_ = cmd.Run()
+			for idx, assert := range c.assertions(cmd) {
+				assertions = append(assertions, fmt.Sprintf("%d. %s", idx+1, indent(assert.describe(), "   ")))
+			}
+			name := cmd.Name
+			if len(cmd.Aliases) > 0 {
+				name += fmt.Sprintf(" (%s)", strings.Join(cmd.Aliases, ", "))
+			}
+			row := []string{name, cmd.Env, cmd.Cli(), cmd.Desc, strings.Join(assertions, "\n")}
+			if len(sources) > 1 {
+				row = append(row, cmd.Source)
+			}
+			row[1] = c.colored(cmd.Env, cmd.Env)
+			table.Append(row)
+		}
+		table.Render()
+	}
+}
 
-	return output.String()
+// groups returns groups in order of first goal in them, goals without group are the last
+func (c *Goals) groups() ([]string, map[string][]Goal) {
+	var groups []string
+	grouped := map[string][]Goal{}
+	for _, goal := range c.Commands {
+		group := withDefault(goal.Group, ungrouped)
+		if _, seen := grouped[group]; !seen && group != ungrouped {
+			groups = append(groups, group)
+		}
+		grouped[group] = append(grouped[group], goal)
+	}
+	if _, any := grouped[ungrouped]; any {
+		groups = append(groups, ungrouped)
+	}
+	return groups, grouped
+}
+
+// newTable creates a table with bold header where the first column is highlighted
+func newTable(header ...string) *tablewriter.Table {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetAutoWrapText(false)
+	headerColors := make([]tablewriter.Colors, len(header))
+	columnColors := make([]tablewriter.Colors, len(header))
+	for i := range header {
+		headerColors[i] = tablewriter.Colors{tablewriter.Bold}
+		columnColors[i] = tablewriter.Colors{tablewriter.Normal}
+	}
+	columnColors[0] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor}
+	table.SetHeaderColor(headerColors...)
+	table.SetColumnColor(columnColors...)
+	return table
 }
 
 func normalizeArgs(args []string) []string {
@@ -150,105 +270,450 @@ func mkAssertions(args []YamlAssert) (assertions []Assertion) {
 		return assertions
 	} else {
 		for _, assertion := range args {
-			if assertion.Ref != "" {
-				assertions = append(assertions, RefAssertion{
-					Desc:   assertion.Desc,
-					Ref:    assertion.Ref,
-					Expect: assertion.Expect,
-					Fix:    assertion.Fix,
-				})
-			} else if assertion.TerraformWorkspace != "" {
-				assertions = append(assertions, TerraformWorkspaceAssertion{
-					Expect: assertion.TerraformWorkspace,
-				})
-			} else if assertion.KubectlContext != "" {
-				assertions = append(assertions, KubectlContextAssertion{
-					Expect: assertion.KubectlContext,
-				})
-			} else if assertion.GcloudProject != "" {
-				assertions = append(assertions, GcloudProjectAssertion{
-					Expect: assertion.GcloudProject,
-				})
-			} else if assertion.Approval != "" {
-				assertions = append(assertions, ApproveAssertion{})
+			if a := mkAssertion(assertion); a != nil {
+				assertions = append(assertions, a)
 			}
 		}
 		return assertions
 	}
 }
 
-func validateAssert(goal string, env string, idx int, assert YamlAssert) {
+func mkAssertion(assertion YamlAssert) Assertion {
+	if assertion.Ref != "" {
+		return RefAssertion{
+			Desc:   assertion.Desc,
+			Ref:    assertion.Ref,
+			Expect: assertion.Expect,
+			Fix:    assertion.Fix,
+		}
+	} else if assertion.TerraformWorkspace != "" {
+		return TerraformWorkspaceAssertion{
+			Expect: assertion.TerraformWorkspace,
+		}
+	} else if assertion.KubectlContext != "" {
+		return KubectlContextAssertion{
+			Expect: assertion.KubectlContext,
+		}
+	} else if assertion.GcloudProject != "" {
+		return GcloudProjectAssertion{
+			Expect: assertion.GcloudProject,
+		}
+	} else if assertion.Approval != "" {
+		return ApproveAssertion{}
+	} else if assertion.EnvVar != nil {
+		return EnvVarAssertion{
+			Name:        assertion.EnvVar.Name,
+			Expect:      assertion.EnvVar.Expect,
+			ExpectRegex: assertion.EnvVar.ExpectRegex,
+			Fix:         assertion.Fix,
+		}
+	} else if assertion.File != nil {
+		return FileAssertion{
+			Path:     assertion.File.Path,
+			Exists:   assertion.File.Exists == nil || *assertion.File.Exists,
+			Contains: assertion.File.Contains,
+			Sha256:   assertion.File.Sha256,
+			Fix:      assertion.Fix,
+		}
+	} else if assertion.ToolVersion != nil {
+		return ToolVersionAssertion{
+			Tool:         assertion.ToolVersion.Tool,
+			Constraint:   assertion.ToolVersion.Constraint,
+			VersionCmd:   assertion.ToolVersion.VersionCmd,
+			VersionRegex: assertion.ToolVersion.VersionRegex,
+			Fix:          assertion.Fix,
+		}
+	} else if assertion.KubectlNamespace != "" {
+		return KubectlNamespaceAssertion{
+			Expect: assertion.KubectlNamespace,
+		}
+	} else if assertion.KubectlServer != nil {
+		return KubectlServerAssertion{
+			Server:   assertion.KubectlServer.Server,
+			CaSha256: assertion.KubectlServer.CaSha256,
+		}
+	} else if assertion.Schedule != nil {
+		return ScheduleAssertion{
+			Timezone: assertion.Schedule.Timezone,
+			Allow:    assertion.Schedule.Allow,
+			Block:    assertion.Schedule.Block,
+			Freeze:   assertion.Schedule.Freeze,
+			Override: assertion.Schedule.Override,
+		}
+	} else if assertion.Who != nil {
+		return WhoAssertion{
+			Identity: assertion.Who.Identity,
+			Allow:    assertion.Who.Allow,
+		}
+	} else if assertion.AnyOf != nil {
+		return AnyOfAssertion{Assertions: mkAssertions(assertion.AnyOf)}
+	} else if assertion.AllOf != nil {
+		return AllOfAssertion{Assertions: mkAssertions(assertion.AllOf)}
+	} else if assertion.Not != nil {
+		return NotAssertion{Assertion: mkAssertion(*assertion.Not)}
+	}
+	for name, config := range assertion.Plugins {
+		return PluginAssertion{
+			Name:   name,
+			Config: jsonCompatible(config),
+			Desc:   assertion.Desc,
+		}
+	}
+	return nil
+}
+
+func validateAssert(path string, assert YamlAssert) {
+	if path, err := assertProblem(path, assert, false); err != "" {
+		Fatal("❗ Malformed %s: %s", path, err)
+	}
+}
+
+// assertProblem returns path to the first malformed (possibly nested) assertion and what is wrong with it
+func assertProblem(path string, assert YamlAssert, nested bool) (string, string) {
 	var err string
-	if assert.Ref == "" && assert.TerraformWorkspace == "" && assert.KubectlContext == "" && assert.GcloudProject == "" && assert.Approval == "" {
-		err = fmt.Sprintf("one of [%s] must be specified for asserion", strings.Join(availableAssertions, ", "))
+	if assert.Ref == "" && assert.TerraformWorkspace == "" && assert.KubectlContext == "" && assert.GcloudProject == "" && assert.Approval == "" &&
+		assert.EnvVar == nil && assert.File == nil && assert.ToolVersion == nil &&
+		assert.KubectlNamespace == "" && assert.KubectlServer == nil && assert.Schedule == nil &&
+		assert.Who == nil && assert.AnyOf == nil && assert.AllOf == nil && assert.Not == nil && len(assert.Plugins) == 0 {
+		err = fmt.Sprintf("one of [%s] must be specified for asserion", strings.Join(assertionKinds(), ", "))
+	}
+	if len(assert.Plugins) > 1 {
+		var names []string
+		for name := range assert.Plugins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		err = fmt.Sprintf("only one plugin assertion is allowed per entry, actual: [%s]", strings.Join(names, ", "))
+	}
+	for name := range assert.Plugins {
+		if msg := unknownAssertion(name, discoverPlugins()); msg != "" {
+			err = msg
+		}
 	}
 	if assert.Approval != "" && assert.Approval != "yes" {
 		err = fmt.Sprintf("for 'approval' assertion 'yes' must be explicitly set as a value: 'approval: yes', actual: 'approval: %s'", assert.Approval)
 	}
+	if assert.Approval != "" && nested {
+		err = "'approval' could not be nested in 'any_of', 'all_of' or 'not'"
+	}
 	if assert.Ref != "" && assert.Expect == "" {
 		err = "for 'ref' assertion specify expected output in 'expect'"
 	}
+	if assert.EnvVar != nil {
+		if msg := validateEnvVarAssert(*assert.EnvVar); msg != "" {
+			err = msg
+		}
+	}
+	if assert.File != nil {
+		if msg := validateFileAssert(*assert.File); msg != "" {
+			err = msg
+		}
+	}
+	if assert.ToolVersion != nil {
+		if msg := validateToolVersionAssert(*assert.ToolVersion); msg != "" {
+			err = msg
+		}
+	}
+	if assert.KubectlServer != nil && assert.KubectlServer.Server == "" && assert.KubectlServer.CaSha256 == "" {
+		err = "for 'kubectl_server' assertion specify 'server' and/or 'ca_sha256'"
+	}
+	if assert.Schedule != nil {
+		if scheduleErr := validateSchedule(*assert.Schedule); scheduleErr != nil {
+			err = fmt.Sprintf("for 'schedule' assertion %s", scheduleErr)
+		}
+	}
+	if assert.Who != nil {
+		if _, known := identities[assert.Who.Identity]; !known {
+			err = fmt.Sprintf("for 'who' assertion 'identity' must be one of [%s], actual: '%s'", strings.Join(identityNames(), ", "), assert.Who.Identity)
+		} else if len(assert.Who.Allow) == 0 {
+			err = "for 'who' assertion specify allowed identities in 'allow'"
+		}
+	}
+	if err != "" {
+		return path, err
+	}
+	composites := []struct {
+		key      string
+		children []YamlAssert
+	}{{"any_of", assert.AnyOf}, {"all_of", assert.AllOf}}
+	for _, composite := range composites {
+		key, children := composite.key, composite.children
+		if children == nil {
+			continue
+		}
+		if len(children) == 0 {
+			return path, fmt.Sprintf("'%s' must contain at least one assertion", key)
+		}
+		for idx, child := range children {
+			if childPath, err := assertProblem(fmt.Sprintf("%s.%s.%d", path, key, idx), child, true); err != "" {
+				return childPath, err
+			}
+		}
+	}
+	if assert.Not != nil {
+		return assertProblem(path+".not", *assert.Not, true)
+	}
+	return path, ""
+}
 
-	if err == "" {
-		return
-	} else {
-		if env == "" {
-			Fatal(fmt.Sprintf("❗ Malformed %s.assert.%d: %s", goal, idx, err))
-		} else {
-			Fatal(fmt.Sprintf("❗ Malformed %s.%s.assert.%d: %s", goal, env, idx, err))
+func validateEnvVarAssert(assert YamlEnvVarAssert) string {
+	if assert.Name == "" {
+		return "for 'env_var' assertion specify variable 'name'"
+	}
+	specified := 0
+	for _, set := range []bool{assert.Expect != "", assert.ExpectRegex != "", assert.Set} {
+		if set {
+			specified++
 		}
 	}
+	if specified > 1 {
+		return "for 'env_var' assertion specify only one of 'expect', 'expect_regex' or 'set'"
+	}
+	if assert.ExpectRegex != "" {
+		if _, err := regexp.Compile(assert.ExpectRegex); err != nil {
+			return fmt.Sprintf("for 'env_var' assertion 'expect_regex' is invalid: %s", err)
+		}
+	}
+	return ""
+}
+
+func validateFileAssert(assert YamlFileAssert) string {
+	if assert.Path == "" {
+		return "for 'file' assertion specify 'path'"
+	}
+	if assert.Exists != nil && !*assert.Exists && (assert.Contains != "" || assert.Sha256 != "") {
+		return "for 'file' assertion 'contains' and 'sha256' could not be used with 'exists: false'"
+	}
+	return ""
 }
 
-func parseEnvCommands(goal string, envs map[string]YamlEnvGoal) []Goal {
+func validateToolVersionAssert(assert YamlToolVersion) string {
+	if assert.Tool == "" {
+		return "for 'tool_version' assertion specify 'tool'"
+	}
+	if assert.Constraint == "" {
+		return "for 'tool_version' assertion specify 'constraint', e.g. 'tool_version: terraform >= 1.3, < 2'"
+	}
+	if _, err := parseVersionConstraint(assert.Constraint); err != nil {
+		return fmt.Sprintf("for 'tool_version' assertion %s", err)
+	}
+	if assert.VersionRegex != "" {
+		if _, err := regexp.Compile(assert.VersionRegex); err != nil {
+			return fmt.Sprintf("for 'tool_version' assertion 'version_regex' is invalid: %s", err)
+		}
+	}
+	return ""
+}
+
+// envVar is substituted in cmd, args, desc and dir of goals, e.g. `-f values/{{ .env }}.yaml`
+var envVar = regexp.MustCompile(`{{\s*\.(env|goal)\s*}}`)
+
+func expandVars(value string, goal string, env string) string {
+	return envVar.ReplaceAllStringFunc(value, func(match string) string {
+		if envVar.FindStringSubmatch(match)[1] == "env" {
+			return env
+		}
+		return goal
+	})
+}
+
+func expandAllVars(values []string, goal string, env string) []string {
+	if values == nil {
+		return nil
+	}
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		expanded[i] = expandVars(value, goal, env)
+	}
+	return expanded
+}
+
+// parseEnvCommands creates a goal per env in declaration order. Cmd, args, assert, desc and dir of the goal are
+// inherited by envs unless overridden, args+ and assert+ are appended to the inherited ones.
+func parseEnvCommands(goal string, defaults YamlGoal, envs []string) []Goal {
 	var commands []Goal
-	for env, envCommand := range envs {
-		args := normalizeArgs(envCommand.Args)
-		if envCommand.Cmd == "" {
+	for _, env := range envs {
+		envCommand := (*defaults.Envs)[env]
+		cmd := withDefault(envCommand.Cmd, defaults.Cmd)
+		if cmd == "" {
 			Fatal("❗ Malformed goals. %s.%s.cmd could not be empty", goal, env)
 		}
-		for idx, assert := range envCommand.Assert {
-			validateAssert(goal, env, idx, assert)
+		args := defaults.Args
+		if envCommand.Args != nil {
+			args = envCommand.Args
 		}
+		args = append(append([]string{}, args...), envCommand.ArgsAppend...)
+		var asserts []YamlAssert
+		if envCommand.Assert != nil {
+			for idx, assert := range envCommand.Assert {
+				validateAssert(fmt.Sprintf("%s.%s.assert.%d", goal, env, idx), assert)
+			}
+			asserts = append(asserts, envCommand.Assert...)
+		} else {
+			asserts = append(asserts, defaults.Assert...)
+		}
+		for idx, assert := range envCommand.AssertAppend {
+			validateAssert(fmt.Sprintf("%s.%s.assert+.%d", goal, env, idx), assert)
+		}
+		asserts = append(asserts, envCommand.AssertAppend...)
 		commands = append(commands, Goal{
-			Name:   goal,
-			Cmd:    envCommand.Cmd,
-			Args:   args,
-			Desc:   envCommand.Desc,
-			Assert: mkAssertions(envCommand.Assert),
-			Env:    env,
+			Name:    goal,
+			Cmd:     expandVars(cmd, goal, env),
+			Args:    expandAllVars(normalizeArgs(args), goal, env),
+			Desc:    expandVars(withDefault(envCommand.Desc, defaults.Desc), goal, env),
+			Assert:  mkAssertions(asserts),
+			Env:     env,
+			Dir:     expandVars(withDefault(envCommand.Dir, defaults.Dir), goal, env),
+			Group:   defaults.Group,
+			Aliases: defaults.Aliases,
+			Hidden:  isHidden(goal, defaults),
 		})
 	}
-	return sortCommands(commands)
+	return commands
+}
+
+// hiddenPrefix marks goals hidden by name, e.g. _current-cluster
+const hiddenPrefix = "_"
+
+func isHidden(name string, goal YamlGoal) bool {
+	return goal.Hidden || strings.HasPrefix(name, hiddenPrefix)
+}
+
+// WithoutHidden returns goals except hidden ones
+func (c *Goals) WithoutHidden() *Goals {
+	var visible []Goal
+	for _, goal := range c.Commands {
+		if !goal.Hidden {
+			visible = append(visible, goal)
+		}
+	}
+	return &Goals{Commands: visible, Environments: c.Environments}
+}
+
+// reservedKeys are top-level keys of goals file that are not goals
+var reservedKeys = map[string]bool{
+	"include":      true,
+	"environments": true,
+}
+
+// yamlGoalEntry is a goal of goals file with its envs in declaration order
+type yamlGoalEntry struct {
+	name string
+	goal YamlGoal
+	envs []string
+}
+
+// parseYamlGoals decodes every top-level key of goals file except reservedKeys, in declaration order
+func parseYamlGoals(bytes []byte) ([]yamlGoalEntry, error) {
+	values := map[string]yamlValue{}
+	if err := yaml.Unmarshal(bytes, &values); err != nil {
+		return nil, err
+	}
+	var order yaml.MapSlice
+	if err := yaml.Unmarshal(bytes, &order); err != nil {
+		return nil, err
+	}
+	var goals []yamlGoalEntry
+	for _, name := range mapSliceKeys(order) {
+		if reservedKeys[name] {
+			continue
+		}
+		value := values[name]
+		var goal YamlGoal
+		if err := value.decode(&goal); err != nil {
+			return nil, fmt.Errorf("goal %s: %s", name, err)
+		}
+		var envs struct {
+			Envs yaml.MapSlice `yaml:"envs"`
+		}
+		if err := value.decode(&envs); err != nil {
+			return nil, fmt.Errorf("goal %s: %s", name, err)
+		}
+		goals = append(goals, yamlGoalEntry{name: name, goal: goal, envs: mapSliceKeys(envs.Envs)})
+	}
+	return goals, nil
 }
 
 // ParseCommands from byte input (YAML)
 func ParseCommands(bytes []byte) (*Goals, error) {
 
-	rawCommands := map[string]YamlGoal{}
-	if err := yaml.Unmarshal(bytes, &rawCommands); err != nil {
+	rawCommands, err := parseYamlGoals(bytes)
+	if err != nil {
 		return nil, err
 	}
 	var res []Goal
-	for name, command := range rawCommands {
+	for _, entry := range rawCommands {
+		name, command := entry.name, entry.goal
+		for idx, assert := range command.Assert {
+			validateAssert(fmt.Sprintf("%s.assert.%d", name, idx), assert)
+		}
 		if command.Envs != nil {
-			res = append(res, parseEnvCommands(name, *command.Envs)...)
+			res = append(res, parseEnvCommands(name, command, entry.envs)...)
 		} else {
-			for idx, assert := range command.Assert {
-				validateAssert(name, "", idx, assert)
-			}
 			args := normalizeArgs(command.Args)
 			res = append(res, Goal{
-				Name:   name,
-				Cmd:    command.Cmd,
-				Args:   args,
-				Desc:   command.Desc,
-				Assert: mkAssertions(command.Assert),
+				Name:    name,
+				Cmd:     expandVars(command.Cmd, name, ""),
+				Args:    expandAllVars(args, name, ""),
+				Desc:    expandVars(command.Desc, name, ""),
+				Assert:  mkAssertions(command.Assert),
+				Dir:     expandVars(command.Dir, name, ""),
+				Group:   command.Group,
+				Aliases: command.Aliases,
+				Hidden:  isHidden(name, command),
 			})
 		}
 	}
 
-	return &Goals{Commands: sortCommands(res)}, nil
+	environments, err := parseEnvironments(bytes)
+	if err != nil {
+		return nil, err
+	}
+	goals := &Goals{Commands: orderCommands(res, environments), Environments: environments}
+	if err := validateAliases(goals.Commands); err != nil {
+		return nil, err
+	}
+	if err := goals.validateEnvironments(); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// orderCommands keeps goals in declaration order. Envs of a goal are listed next to each other,
+// in order of top-level environments when declared there.
+func orderCommands(commands []Goal, environments []Environment) []Goal {
+	position := map[string]int{}
+	for i, env := range environments {
+		position[env.Name] = i
+	}
+	envPosition := func(env string) int {
+		if i, declared := position[env]; declared {
+			return i
+		}
+		return len(environments)
+	}
+	var names []string
+	variants := map[string][]Goal{}
+	for _, goal := range commands {
+		if _, seen := variants[goal.Name]; !seen {
+			names = append(names, goal.Name)
+		}
+		variants[goal.Name] = append(variants[goal.Name], goal)
+	}
+	var ordered []Goal
+	for _, name := range names {
+		goals := variants[name]
+		sort.SliceStable(goals, func(i, j int) bool {
+			return envPosition(goals[i].Env) < envPosition(goals[j].Env)
+		})
+		ordered = append(ordered, goals...)
+	}
+	return ordered
+}
+
+// SortByName lists goals alphabetically instead of declaration order
+func (c *Goals) SortByName() {
+	c.Commands = sortCommands(c.Commands)
 }
 
 func sortCommands(commands []Goal) (sorted []Goal) {
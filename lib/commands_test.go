@@ -158,6 +158,210 @@ apply:
 			},
 			wantErr: false,
 		},
+		{
+			name: "Env var and file assertions",
+			args: args{bytes: []byte(`
+apply:
+  desc: tf apply prod
+  assert:
+    - env_var:
+        name: KUBECONFIG
+        expect_regex: prod
+    - file:
+        path: vars/prod.tfvars
+    - file:
+        path: .terraform/environment
+        contains: prod
+      fix: terraform workspace select prod
+  cmd: terraform
+  args:
+    - apply
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name: "apply",
+						Cmd:  "terraform",
+						Args: []string{"apply"},
+						Assert: []Assertion{
+							EnvVarAssertion{Name: "KUBECONFIG", ExpectRegex: "prod"},
+							FileAssertion{Path: "vars/prod.tfvars", Exists: true},
+							FileAssertion{
+								Path:     ".terraform/environment",
+								Exists:   true,
+								Contains: "prod",
+								Fix:      "terraform workspace select prod",
+							},
+						},
+						Desc: "tf apply prod",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Tool version assertions",
+			args: args{bytes: []byte(`
+apply:
+  assert:
+    - tool_version: terraform >= 1.3, < 2
+    - tool_version:
+        tool: node
+        constraint: ~> 18.0
+        version_cmd: node --version
+  cmd: terraform
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name: "apply",
+						Cmd:  "terraform",
+						Args: []string{},
+						Assert: []Assertion{
+							ToolVersionAssertion{Tool: "terraform", Constraint: ">= 1.3, < 2"},
+							ToolVersionAssertion{Tool: "node", Constraint: "~> 18.0", VersionCmd: "node --version"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Kubectl namespace and server assertions",
+			args: args{bytes: []byte(`
+apply:
+  assert:
+    - kubectl_namespace: payments
+    - kubectl_server: https://10.0.0.1
+    - kubectl_server:
+        ca_sha256: AB:CD
+  cmd: kubectl
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name: "apply",
+						Cmd:  "kubectl",
+						Args: []string{},
+						Assert: []Assertion{
+							KubectlNamespaceAssertion{Expect: "payments"},
+							KubectlServerAssertion{Server: "https://10.0.0.1"},
+							KubectlServerAssertion{CaSha256: "AB:CD"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Composite assertions",
+			args: args{bytes: []byte(`
+apply:
+  assert:
+    - any_of:
+        - kubectl_context: stage-eu
+        - kubectl_context: stage-us
+    - not:
+        gcloud_project: prod
+  cmd: kubectl
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name: "apply",
+						Cmd:  "kubectl",
+						Args: []string{},
+						Assert: []Assertion{
+							AnyOfAssertion{Assertions: []Assertion{
+								KubectlContextAssertion{Expect: "stage-eu"},
+								KubectlContextAssertion{Expect: "stage-us"},
+							}},
+							NotAssertion{Assertion: GcloudProjectAssertion{Expect: "prod"}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Include is not a goal",
+			args: args{bytes: []byte(`
+include:
+  - ops/k8s.goal.yaml
+apply:
+  assert:
+    - approval: yes
+  cmd: kubectl
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name:   "apply",
+						Cmd:    "kubectl",
+						Args:   []string{},
+						Assert: []Assertion{ApproveAssertion{}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Envs inherit goal defaults",
+			args: args{bytes: []byte(`
+upgrade:
+  desc: Upgrade on {{ .env }}
+  cmd: helm
+  args:
+    - upgrade
+    - -f
+    - values/{{ .env }}.yaml
+  assert:
+    - kubectl_context: dev
+  envs:
+    dev: {}
+    prod:
+      desc: Upgrade production
+      args+:
+        - --atomic
+      assert+:
+        - approval: yes
+    stage:
+      cmd: echo
+      args:
+        - "{{.goal}}"
+      assert:
+        - kubectl_context: stage
+`)},
+			want: &Goals{
+				Commands: []Goal{
+					{
+						Name:   "upgrade",
+						Env:    "dev",
+						Cmd:    "helm",
+						Args:   []string{"upgrade", "-f", "values/dev.yaml"},
+						Desc:   "Upgrade on dev",
+						Assert: []Assertion{KubectlContextAssertion{Expect: "dev"}},
+					},
+					{
+						Name:   "upgrade",
+						Env:    "prod",
+						Cmd:    "helm",
+						Args:   []string{"upgrade", "-f", "values/prod.yaml", "--atomic"},
+						Desc:   "Upgrade production",
+						Assert: []Assertion{KubectlContextAssertion{Expect: "dev"}, ApproveAssertion{}},
+					},
+					{
+						Name:   "upgrade",
+						Env:    "stage",
+						Cmd:    "echo",
+						Args:   []string{"upgrade"},
+						Desc:   "Upgrade on stage",
+						Assert: []Assertion{KubectlContextAssertion{Expect: "stage"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -212,6 +416,8 @@ func TestCommand_Cli(t *testing.T) {
 		{name: "no argument", fields: fields{Cmd: "echo", Args: []string{}}, want: "echo"},
 		{name: "single argument", fields: fields{Cmd: "echo", Args: []string{"123"}}, want: "echo 123"},
 		{name: "single argument + flag", fields: fields{Cmd: "echo", Args: []string{"-n", "123"}}, want: "echo -n 123"},
+		{name: "shell script", fields: fields{Cmd: "sh", Args: []string{"-c", "golangci-lint run | tee lint.txt"}}, want: "sh -c 'golangci-lint run | tee lint.txt'"},
+		{name: "quotes and empty", fields: fields{Cmd: "echo", Args: []string{"it's", ""}}, want: `echo 'it'\''s' ''`},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -359,3 +565,112 @@ func TestCommands_GetWithEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommands_order(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "declaration order",
+			yaml: `
+init: {cmd: terraform}
+plan: {cmd: terraform}
+apply: {cmd: terraform}
+`,
+			want: []string{"init", "plan", "apply"},
+		},
+		{
+			name: "envs in declaration order",
+			yaml: `
+apply:
+  cmd: terraform
+  envs:
+    stage: {}
+    prod: {}
+    dev: {}
+`,
+			want: []string{"apply on stage", "apply on prod", "apply on dev"},
+		},
+		{
+			name: "envs in order of environments",
+			yaml: `
+environments:
+  dev: {}
+  stage: {}
+  prod: {}
+plan:
+  cmd: terraform
+  envs:
+    prod: {}
+    dev: {}
+apply:
+  cmd: terraform
+  envs:
+    stage: {}
+    dev: {}
+`,
+			want: []string{"plan on dev", "plan on prod", "apply on dev", "apply on stage"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goals, err := ParseCommands([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, goal := range goals.Commands {
+				got = append(got, goalTitle(goal))
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("ParseCommands() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoals_groups(t *testing.T) {
+	goals := &Goals{Commands: []Goal{
+		{Name: "debug", Group: "Debug"},
+		{Name: "lint"},
+		{Name: "plan", Group: "Terraform"},
+		{Name: "pods", Group: "Debug"},
+	}}
+	groups, grouped := goals.groups()
+	if want := []string{"Debug", "Terraform", ungrouped}; !cmp.Equal(groups, want) {
+		t.Errorf("groups() = %v, want %v", groups, want)
+	}
+	if got := len(grouped["Debug"]); got != 2 {
+		t.Errorf("groups() Debug has %d goals, want 2", got)
+	}
+}
+
+func TestParseCommands_hidden(t *testing.T) {
+	goals, err := ParseCommands([]byte(`
+current-cluster:
+  hidden: true
+  cmd: kubectl
+_tf-workspace:
+  cmd: terraform
+apply:
+  assert:
+    - ref: current-cluster
+      expect: dev
+  cmd: kubectl
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visible []string
+	for _, goal := range goals.WithoutHidden().Commands {
+		visible = append(visible, goal.Name)
+	}
+	if want := []string{"apply"}; !cmp.Equal(visible, want) {
+		t.Errorf("WithoutHidden() = %v, want %v", visible, want)
+	}
+	if _, exists := goals.get("current-cluster"); !exists {
+		t.Errorf("get() of hidden goal does not exist")
+	}
+}
@@ -0,0 +1,131 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// GoalFileName is looked up in current directory and its parents
+const GoalFileName = "goal.yaml"
+
+// parentDirs returns start and its parents up to the repository root (directory containing .git),
+// or up to the filesystem root outside of a repository. Nearest directory goes first.
+func parentDirs(start string) []string {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return []string{start}
+	}
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dirs
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
+
+// FindGoalFiles returns goal.yaml files found in start and its parents up to the repository root, nearest first.
+// Paths are relative to start when possible.
+func FindGoalFiles(start string) []string {
+	var files []string
+	if abs, err := filepath.Abs(start); err == nil {
+		start = abs
+	}
+	for _, dir := range parentDirs(start) {
+		path := filepath.Join(dir, GoalFileName)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		if rel, err := filepath.Rel(start, path); err == nil {
+			path = rel
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+// ParseSource parses goals file source. Goal directories are resolved against the directory of source.
+func ParseSource(source string, bytes []byte) (*Goals, error) {
+	base, err := filepath.Abs(filepath.Dir(source))
+	if err != nil {
+		return nil, err
+	}
+	return parseSource(source, bytes, base)
+}
+
+// parseSource parses goals file source with goal directories resolved against base, left as they are when base is empty
+func parseSource(source string, bytes []byte, base string) (*Goals, error) {
+	goals, err := ParseCommands(bytes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals.Commands {
+		goal := &goals.Commands[i]
+		goal.Source = source
+		if base == "" {
+			continue
+		}
+		if goal.Dir == "" {
+			goal.Dir = base
+		} else if !filepath.IsAbs(goal.Dir) {
+			goal.Dir = filepath.Join(base, goal.Dir)
+		}
+	}
+	for i := range goals.Environments {
+		goals.Environments[i].Source = source
+	}
+	return goals, nil
+}
+
+// MergeGoals combines goals of several files, nearest first. A goal defined in a nearer file
+// replaces the goal with the same name (including all its environments) from farther files.
+func MergeGoals(nearestFirst ...*Goals) *Goals {
+	defined := map[string]bool{}
+	var merged []Goal
+	for _, goals := range nearestFirst {
+		names := map[string]bool{}
+		for _, goal := range goals.Commands {
+			if defined[goal.Name] {
+				continue
+			}
+			names[goal.Name] = true
+			merged = append(merged, goal)
+		}
+		for name := range names {
+			defined[name] = true
+		}
+	}
+	declared := map[string]bool{}
+	var environments []Environment
+	for _, goals := range nearestFirst {
+		for _, env := range goals.Environments {
+			if !declared[env.Name] {
+				declared[env.Name] = true
+				environments = append(environments, env)
+			}
+		}
+	}
+	return &Goals{Commands: orderCommands(merged, environments), Environments: environments}
+}
+
+// inDir runs fn with dir as working directory, current directory is restored afterwards
+func inDir(dir string, fn func()) {
+	if dir == "" {
+		fn()
+		return
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		Fatal("❗ Failed to get current directory: %s", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		Fatal("❗ Failed to enter goal directory %s: %s", dir, err)
+	}
+	defer func() { _ = os.Chdir(previous) }()
+	fn()
+}
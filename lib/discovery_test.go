@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindGoalFiles(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "infra", "modules", "db")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// outside of repository, must not be found
+	writeFile(t, filepath.Join(root, GoalFileName), "")
+	writeFile(t, filepath.Join(repo, GoalFileName), "")
+	writeFile(t, filepath.Join(repo, "infra", GoalFileName), "")
+
+	got := FindGoalFiles(nested)
+	want := []string{
+		filepath.Join("..", "..", GoalFileName),
+		filepath.Join("..", "..", "..", GoalFileName),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindGoalFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, GoalFileName)
+	goals, err := ParseSource(source, []byte(`
+test:
+  cmd: go
+plan:
+  dir: infra
+  envs:
+    dev:
+      cmd: terraform
+    stage:
+      cmd: terraform
+      dir: /tmp
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"plan/dev":   filepath.Join(dir, "infra"),
+		"plan/stage": "/tmp",
+		"test/":      dir,
+	}
+	for _, goal := range goals.Commands {
+		if goal.Source != source {
+			t.Errorf("%s source = %v, want %v", goal.Name, goal.Source, source)
+		}
+		if key := goal.Name + "/" + goal.Env; goal.Dir != want[key] {
+			t.Errorf("%s dir = %v, want %v", key, goal.Dir, want[key])
+		}
+	}
+}
+
+func TestMergeGoals(t *testing.T) {
+	near := &Goals{Commands: []Goal{
+		{Name: "apply", Env: "dev", Cmd: "near", Source: "goal.yaml"},
+	}}
+	far := &Goals{Commands: []Goal{
+		{Name: "apply", Env: "dev", Cmd: "far", Source: "../goal.yaml"},
+		{Name: "apply", Env: "stage", Cmd: "far", Source: "../goal.yaml"},
+		{Name: "lint", Cmd: "far", Source: "../goal.yaml"},
+	}}
+	got := MergeGoals(near, far)
+	want := []Goal{
+		{Name: "apply", Env: "dev", Cmd: "near", Source: "goal.yaml"},
+		{Name: "lint", Cmd: "far", Source: "../goal.yaml"},
+	}
+	if !reflect.DeepEqual(got.Commands, want) {
+		t.Errorf("MergeGoals() = %v, want %v", got.Commands, want)
+	}
+}
@@ -0,0 +1,260 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// fileFlags take a path to a file that must exist, e.g. `terraform apply -var-file vars/dev.tfvars`
+var fileFlags = map[string]bool{
+	"-var-file":  true,
+	"--var-file": true,
+	"-f":         true,
+	"--filename": true,
+	"--values":   true,
+	"--env-file": true,
+}
+
+// ToolStatus tells whether executable Name is installed
+type ToolStatus struct {
+	Name string `json:"name"`
+	// Path is the resolved executable, empty when it is missing
+	Path string `json:"path,omitempty"`
+	// Version is known only for tools goal knows how to ask, see knownTools
+	Version string `json:"version,omitempty"`
+	// UsedBy lists goals that need the tool
+	UsedBy []string `json:"used_by"`
+}
+
+// FileStatus tells whether file Path referenced in args of goal UsedBy exists
+type FileStatus struct {
+	Path    string `json:"path"`
+	UsedBy  string `json:"used_by"`
+	Missing bool   `json:"missing"`
+}
+
+// DoctorReport lists tools and files goals depend on, in order of first use
+type DoctorReport struct {
+	Tools []ToolStatus `json:"tools"`
+	Files []FileStatus `json:"files"`
+}
+
+// Doctor finds executables and files required by goals and checks they are present.
+// Versions are probed only for tools with a known version command, goal commands are never run.
+func (c *Goals) Doctor() DoctorReport {
+	var report DoctorReport
+	index := map[string]int{}
+	versions := map[string]ToolVersionAssertion{}
+	use := func(tool string, goal Goal) {
+		if tool == "" {
+			return
+		}
+		i, seen := index[tool]
+		if !seen {
+			i = len(report.Tools)
+			index[tool] = i
+			report.Tools = append(report.Tools, ToolStatus{Name: tool})
+		}
+		usedBy := goalTitle(goal)
+		for _, existing := range report.Tools[i].UsedBy {
+			if existing == usedBy {
+				return
+			}
+		}
+		report.Tools[i].UsedBy = append(report.Tools[i].UsedBy, usedBy)
+	}
+	for _, goal := range c.Commands {
+		use(goalTool(goal, goal.Cmd), goal)
+		for _, assert := range c.assertions(goal) {
+			for _, tool := range c.assertionTools(assert, versions) {
+				use(goalTool(goal, tool), goal)
+			}
+		}
+		for _, path := range fileArgs(goal.Args) {
+			if goal.Dir != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(goal.Dir, path)
+			}
+			_, err := os.Stat(path)
+			report.Files = append(report.Files, FileStatus{Path: path, UsedBy: goalTitle(goal), Missing: err != nil})
+		}
+	}
+	for i := range report.Tools {
+		tool := &report.Tools[i]
+		tool.Path = resolveTool(tool.Name)
+		if tool.Path == "" {
+			continue
+		}
+		if assert, exists := versions[tool.Name]; exists {
+			tool.Version = installedVersion(assert.Tool, assert.VersionCmd, assert.VersionRegex)
+		} else if _, known := knownTools[tool.Name]; known {
+			tool.Version = installedVersion(tool.Name, "", "")
+		}
+	}
+	return report
+}
+
+func goalTitle(goal Goal) string {
+	if goal.Env == "" {
+		return goal.Name
+	}
+	return goal.Name + " on " + goal.Env
+}
+
+// goalTool resolves relative path of executable, e.g. `./scripts/deploy.sh`, against directory goal runs in
+func goalTool(goal Goal, tool string) string {
+	if goal.Dir == "" || filepath.IsAbs(tool) || !strings.ContainsAny(tool, `/\`) {
+		return tool
+	}
+	path := filepath.Join(goal.Dir, tool)
+	if !strings.ContainsRune(path, filepath.Separator) {
+		// not to be looked up in PATH
+		path = "." + string(filepath.Separator) + path
+	}
+	return path
+}
+
+// assertionTools returns executables assert may run, including command of ref target.
+// Version commands of tool_version assertions are collected in versions.
+func (c *Goals) assertionTools(assert Assertion, versions map[string]ToolVersionAssertion) []string {
+	switch a := assert.(type) {
+	case RefAssertion:
+		for _, goal := range c.Commands {
+			if goal.Name == a.Ref && goal.Env == "" {
+				return []string{goal.Cmd}
+			}
+		}
+	case TerraformWorkspaceAssertion:
+		return []string{"terraform"}
+	case KubectlContextAssertion, KubectlNamespaceAssertion, KubectlServerAssertion:
+		return []string{"kubectl"}
+	case GcloudProjectAssertion:
+		return []string{"gcloud"}
+	case ToolVersionAssertion:
+		name, _, _ := versionProbe(a.Tool, a.VersionCmd, a.VersionRegex)
+		versions[name] = a
+		return []string{name}
+	case WhoAssertion:
+		return []string{identities[a.Identity].Executable}
+	case PluginAssertion:
+		return []string{pluginExecutable(a.Name)}
+	case AnyOfAssertion:
+		return c.childTools(a.Assertions, versions)
+	case AllOfAssertion:
+		return c.childTools(a.Assertions, versions)
+	case NotAssertion:
+		return c.assertionTools(a.Assertion, versions)
+	}
+	return nil
+}
+
+func (c *Goals) childTools(assertions []Assertion, versions map[string]ToolVersionAssertion) []string {
+	var tools []string
+	for _, assert := range assertions {
+		tools = append(tools, c.assertionTools(assert, versions)...)
+	}
+	return tools
+}
+
+func resolveTool(name string) string {
+	if strings.HasPrefix(name, pluginPrefix) {
+		return findPlugin(strings.TrimSuffix(strings.TrimPrefix(name, pluginPrefix), ".exe"))
+	}
+	path, err := osexec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func installedVersion(tool string, versionCmd string, versionRegex string) string {
+	name, args, extract := versionProbe(tool, versionCmd, versionRegex)
+	out, err := probe(name, args...)
+	if err != nil {
+		return ""
+	}
+	version, err := extract(out)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+// fileArgs returns values of fileFlags in args, both `-f file` and `-f=file` forms
+func fileArgs(args []string) []string {
+	var files []string
+	for i, arg := range args {
+		value := ""
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 && fileFlags[parts[0]] {
+			value = parts[1]
+		} else if fileFlags[arg] && i+1 < len(args) {
+			value = args[i+1]
+		}
+		// "-" is stdin, URLs are fetched by the tool itself
+		if value != "" && value != "-" && !strings.Contains(value, "://") {
+			files = append(files, value)
+		}
+	}
+	return files
+}
+
+// missing counts tools and files that are not present
+func (r DoctorReport) missing() (tools int, files int) {
+	for _, tool := range r.Tools {
+		if tool.Path == "" {
+			tools++
+		}
+	}
+	for _, file := range r.Files {
+		if file.Missing {
+			files++
+		}
+	}
+	return tools, files
+}
+
+// Healthy is true when all tools and files are present
+func (r DoctorReport) Healthy() bool {
+	tools, files := r.missing()
+	return tools == 0 && files == 0
+}
+
+func (r DoctorReport) RenderJson() {
+	renderJson(r)
+}
+
+func (r DoctorReport) Render() {
+	Info("Tools:")
+	tools := newTable("Tool", "Status", "Path", "Version", "Used by")
+	for _, tool := range r.Tools {
+		status := "✅ found"
+		if tool.Path == "" {
+			status = "❌ missing"
+		}
+		tools.Append([]string{tool.Name, status, tool.Path, tool.Version, strings.Join(tool.UsedBy, "\n")})
+	}
+	tools.Render()
+	if len(r.Files) == 0 {
+		return
+	}
+	Info("Files:")
+	files := newTable("File", "Status", "Used by")
+	for _, file := range r.Files {
+		status := "✅ found"
+		if file.Missing {
+			status = "❌ missing"
+		}
+		files.Append([]string{file.Path, status, file.UsedBy})
+	}
+	files.Render()
+}
+
+func (r DoctorReport) Summary() string {
+	tools, files := r.missing()
+	if tools == 0 && files == 0 {
+		return "✅ All tools and files are in place"
+	}
+	return fmt.Sprintf("❗ %d tool(s) and %d file(s) are missing", tools, files)
+}
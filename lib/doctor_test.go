@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestFileArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "terraform", args: []string{"apply", "-var-file", "vars/dev.tfvars"}, want: []string{"vars/dev.tfvars"}},
+		{name: "equals form", args: []string{"apply", "-var-file=vars/dev.tfvars"}, want: []string{"vars/dev.tfvars"}},
+		{name: "helm", args: []string{"upgrade", "app", "-f", "values.yaml", "--values", "values/stage.yaml", "."}, want: []string{"values.yaml", "values/stage.yaml"}},
+		{name: "stdin and urls", args: []string{"apply", "-f", "-", "-f", "https://example.com/app.yaml"}, want: nil},
+		{name: "flag without value", args: []string{"apply", "-f"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fileArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fileArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoals_Doctor(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "dev.tfvars")
+	writeFile(t, existing, "")
+	goals := Goals{Commands: []Goal{
+		{Name: "test", Cmd: "go", Args: []string{"test", "./..."}},
+		{Name: "apply", Env: "dev", Cmd: "goal-definitely-not-installed", Args: []string{"-var-file", existing}, Assert: []Assertion{
+			AnyOfAssertion{Assertions: []Assertion{KubectlContextAssertion{Expect: "dev"}}},
+			ToolVersionAssertion{Tool: "go", VersionCmd: "go version", VersionRegex: `go(\d+\.\d+(?:\.\d+)?)`},
+		}},
+		{Name: "apply", Env: "stage", Cmd: "goal-definitely-not-installed", Args: []string{"-var-file", filepath.Join(dir, "stage.tfvars")}, Assert: []Assertion{
+			PluginAssertion{Name: "definitely_not_installed"},
+		}},
+	}}
+
+	report := goals.Doctor()
+
+	var names []string
+	for _, tool := range report.Tools {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"go", "goal-definitely-not-installed", "kubectl", "goal-assert-definitely_not_installed"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Doctor() tools = %v, want %v", names, want)
+	}
+	if tool := report.Tools[0]; tool.Path == "" || tool.Version == "" || !reflect.DeepEqual(tool.UsedBy, []string{"test", "apply on dev"}) {
+		t.Errorf("Doctor() go = %+v, want resolved path, version and both goals", tool)
+	}
+	if tool := report.Tools[1]; tool.Path != "" || !reflect.DeepEqual(tool.UsedBy, []string{"apply on dev", "apply on stage"}) {
+		t.Errorf("Doctor() missing tool = %+v", tool)
+	}
+	if tool := report.Tools[3]; tool.Path != "" {
+		t.Errorf("Doctor() missing plugin = %+v", tool)
+	}
+	if len(report.Files) != 2 || report.Files[0].Missing || !report.Files[1].Missing {
+		t.Errorf("Doctor() files = %+v, want dev present and stage missing", report.Files)
+	}
+	if report.Healthy() {
+		t.Errorf("Healthy() = true, want false")
+	}
+}
+
+func TestGoals_Doctor_refAndRelativeCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script requires sh")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "deploy.sh")
+	writeFile(t, script, "#!/bin/sh\n")
+	if err := os.Chmod(script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	goals := Goals{Commands: []Goal{
+		{Name: "version", Cmd: "go", Args: []string{"version"}},
+		{Name: "deploy", Cmd: "./deploy.sh", Dir: dir, Assert: []Assertion{RefAssertion{Ref: "version", Expect: "go1.17"}}},
+	}}
+
+	report := goals.Doctor()
+
+	if len(report.Tools) != 2 {
+		t.Fatalf("Doctor() tools = %+v, want go and deploy.sh", report.Tools)
+	}
+	if tool := report.Tools[0]; !reflect.DeepEqual(tool.UsedBy, []string{"version", "deploy"}) {
+		t.Errorf("Doctor() ref target tool = %+v, want used by goal referencing it too", tool)
+	}
+	if tool := report.Tools[1]; tool.Name != script || tool.Path == "" {
+		t.Errorf("Doctor() relative tool = %+v, want resolved in goal directory", tool)
+	}
+}
@@ -0,0 +1,433 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// GoalSpec describes a goal added to goals file, on Env when not empty
+type GoalSpec struct {
+	Name   string
+	Env    string
+	Desc   string
+	Cmd    string
+	Args   []string
+	Dir    string
+	Assert []YamlAssert
+}
+
+// ParseAssert parses a single assertion written as YAML mapping, e.g. `terraform_workspace: dev` or `{ref: workspace, expect: dev}`
+func ParseAssert(text string) (YamlAssert, error) {
+	var assert YamlAssert
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil || len(doc.Content) == 0 {
+		return assert, fmt.Errorf("invalid assertion '%s', expected e.g. 'terraform_workspace: dev'", text)
+	}
+	v := &validator{}
+	v.value(doc.Content[0], yamlAssertType, "assert")
+	if len(v.problems) > 0 {
+		return assert, fmt.Errorf("invalid assertion '%s': %s", text, v.problems[0].Message)
+	}
+	err := yamlv2.Unmarshal([]byte(text), &assert)
+	return assert, err
+}
+
+// goalsText is goals file split into lines (with line endings) and parsed into nodes to find where goals are.
+// Goals are edited by splicing lines, so comments, ordering and formatting of other goals are kept intact.
+type goalsText struct {
+	lines []string
+	root  *yaml.Node
+}
+
+func parseGoalsText(content []byte) (*goalsText, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	text := &goalsText{lines: strings.SplitAfter(string(content), "\n")}
+	if last := len(text.lines) - 1; text.lines[last] == "" {
+		text.lines = text.lines[:last]
+	}
+	if len(doc.Content) == 0 {
+		return text, nil
+	}
+	text.root = doc.Content[0]
+	if text.root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("goals file must be a mapping of goal names to goals, got %s", nodeKind(text.root))
+	}
+	if text.root.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("goals file in flow style could not be edited")
+	}
+	return text, nil
+}
+
+func (t *goalsText) String() string {
+	return strings.Join(t.lines, "")
+}
+
+// entry finds key in mapping node, returns its index in node.Content or -1
+func entry(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// entryLines returns first and last line (1-based) of i-th entry of mapping which ends before line parentEnd+1.
+// Trailing blank lines and comments of the next entries are not included.
+func (t *goalsText) entryLines(mapping *yaml.Node, i int, parentEnd int) (int, int) {
+	key := mapping.Content[i]
+	end := parentEnd
+	if i+2 < len(mapping.Content) {
+		end = mapping.Content[i+2].Line - 1
+	}
+	for end > key.Line && (isBlank(t.lines[end-1]) || (isComment(t.lines[end-1]) && indentOf(t.lines[end-1]) < key.Column)) {
+		end--
+	}
+	return key.Line, end
+}
+
+// headStart extends entry starting at line with comment lines right above it.
+// Comments starting the file are its header, not comments of the first entry.
+func (t *goalsText) headStart(line int, column int) int {
+	start := line
+	for start > 1 && isComment(t.lines[start-2]) && indentOf(t.lines[start-2]) == column-1 {
+		start--
+	}
+	if start == 1 {
+		return line
+	}
+	return start
+}
+
+// remove deletes lines from first to last, dropping a blank line left doubled
+func (t *goalsText) remove(first int, last int) {
+	if last < len(t.lines) && isBlank(t.lines[last]) && (first == 1 || isBlank(t.lines[first-2])) {
+		last++
+	}
+	if last == len(t.lines) && first > 1 && isBlank(t.lines[first-2]) {
+		// no blank line at the end of file
+		first--
+	}
+	t.lines = append(t.lines[:first-1], t.lines[last:]...)
+}
+
+// insert adds block after line
+func (t *goalsText) insert(after int, block string) {
+	if after > 0 && !strings.HasSuffix(t.lines[after-1], "\n") {
+		t.lines[after-1] += "\n"
+	}
+	inserted := strings.SplitAfter(block, "\n")
+	if inserted[len(inserted)-1] == "" {
+		inserted = inserted[:len(inserted)-1]
+	}
+	t.lines = append(t.lines[:after], append(inserted, t.lines[after:]...)...)
+}
+
+// indentWidth is indentation of goals file, 2 by default
+func (t *goalsText) indentWidth() int {
+	if t.root == nil {
+		return 2
+	}
+	for i := 1; i < len(t.root.Content); i += 2 {
+		if goal := t.root.Content[i]; goal.Kind == yaml.MappingNode && goal.Style&yaml.FlowStyle == 0 && len(goal.Content) > 0 {
+			return goal.Content[0].Column - t.root.Content[i-1].Column
+		}
+	}
+	return 2
+}
+
+// separated tells whether goals in file are separated by blank lines
+func (t *goalsText) separated() bool {
+	if t.root == nil {
+		return false
+	}
+	for i := 2; i < len(t.root.Content); i += 2 {
+		key := t.root.Content[i]
+		line := t.headStart(key.Line, key.Column)
+		if line > 1 && isBlank(t.lines[line-2]) {
+			return true
+		}
+	}
+	return false
+}
+
+// envsEnd returns last line of envs of i-th goal
+func (t *goalsText) envsEnd(i int) int {
+	goal := t.root.Content[i+1]
+	_, goalEnd := t.entryLines(t.root, i, len(t.lines))
+	_, end := t.entryLines(goal, entry(goal, "envs"), goalEnd)
+	return end
+}
+
+// AddGoal adds goal to content of goals file. Env of spec is added to envs of existing goal.
+func AddGoal(content []byte, spec GoalSpec) ([]byte, error) {
+	if spec.Name == "" || reservedKeys[spec.Name] {
+		return nil, fmt.Errorf("invalid goal name '%s'", spec.Name)
+	}
+	if spec.Cmd == "" {
+		return nil, fmt.Errorf("%s.cmd could not be empty", spec.Name)
+	}
+	text, err := parseGoalsText(content)
+	if err != nil {
+		return nil, err
+	}
+	indent := text.indentWidth()
+	env := YamlEnvGoal{Desc: spec.Desc, Cmd: spec.Cmd, Args: spec.Args, Dir: spec.Dir, Assert: spec.Assert}
+	i := entry(text.root, spec.Name)
+	if i < 0 {
+		goal := YamlGoal{Desc: spec.Desc, Cmd: spec.Cmd, Args: spec.Args, Dir: spec.Dir, Assert: spec.Assert}
+		if spec.Env != "" {
+			goal = YamlGoal{Envs: &map[string]YamlEnvGoal{spec.Env: env}}
+		}
+		block, err := encodeBlock(map[string]YamlGoal{spec.Name: goal}, indent, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(text.lines) > 0 && !isBlank(text.lines[len(text.lines)-1]) && text.separated() {
+			block = "\n" + block
+		}
+		text.insert(len(text.lines), block)
+		return []byte(text.String()), nil
+	}
+	if spec.Env == "" {
+		return nil, fmt.Errorf("goal %s already exists", spec.Name)
+	}
+	goal := text.root.Content[i+1]
+	envs := mappingValue(goal, "envs")
+	if envs == nil || envs.Kind != yaml.MappingNode || len(envs.Content) == 0 {
+		return nil, fmt.Errorf("goal %s has no envs, remove it first to add it on %s", spec.Name, spec.Env)
+	}
+	if envs.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("envs of goal %s are in flow style and could not be edited", spec.Name)
+	}
+	if entry(envs, spec.Env) >= 0 {
+		return nil, fmt.Errorf("goal %s already exists on %s", spec.Name, spec.Env)
+	}
+	_, envsEnd := text.entryLines(envs, len(envs.Content)-2, text.envsEnd(i))
+	block, err := encodeBlock(map[string]YamlEnvGoal{spec.Env: env}, indent, envs.Content[0].Column-1)
+	if err != nil {
+		return nil, err
+	}
+	text.insert(envsEnd, block)
+	return []byte(text.String()), nil
+}
+
+// RemoveGoal removes goal from content of goals file, or only its env when env is not empty.
+// Goal is removed entirely together with its last env.
+func RemoveGoal(content []byte, name string, env string) ([]byte, error) {
+	text, err := parseGoalsText(content)
+	if err != nil {
+		return nil, err
+	}
+	i := entry(text.root, name)
+	if i < 0 || reservedKeys[name] {
+		return nil, fmt.Errorf("no such goal: %s", name)
+	}
+	first, last := text.entryLines(text.root, i, len(text.lines))
+	if env != "" {
+		envs := mappingValue(text.root.Content[i+1], "envs")
+		j := entry(envs, env)
+		if j < 0 {
+			return nil, fmt.Errorf("no such environment of goal %s: %s", name, env)
+		}
+		if len(envs.Content) > 2 {
+			if envs.Style&yaml.FlowStyle != 0 {
+				return nil, fmt.Errorf("envs of goal %s are in flow style and could not be edited", name)
+			}
+			envFirst, envLast := text.entryLines(envs, j, text.envsEnd(i))
+			key := envs.Content[j]
+			text.remove(text.headStart(envFirst, key.Column), envLast)
+			return []byte(text.String()), nil
+		}
+	}
+	text.remove(text.headStart(first, text.root.Content[i].Column), last)
+	return []byte(text.String()), nil
+}
+
+// goalField is a key of goal or env set (or removed when value is nil) by EditGoal
+type goalField struct {
+	key   string
+	value interface{}
+}
+
+// EditGoal changes goal, or its env when spec.Env is set, to fields set in spec: desc, cmd together with args, assert.
+// Other fields, comments and formatting of the rest of the file are kept.
+func EditGoal(content []byte, spec GoalSpec) ([]byte, error) {
+	var fields []goalField
+	if spec.Desc != "" {
+		fields = append(fields, goalField{"desc", spec.Desc})
+	}
+	if spec.Cmd != "" {
+		fields = append(fields, goalField{"cmd", spec.Cmd})
+		if len(spec.Args) > 0 {
+			fields = append(fields, goalField{"args", spec.Args})
+		} else {
+			fields = append(fields, goalField{"args", nil})
+		}
+	}
+	if spec.Assert != nil {
+		fields = append(fields, goalField{"assert", spec.Assert})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("nothing to change in goal %s, specify description, command or assertions", spec.Name)
+	}
+	fieldsType := yamlGoalType
+	if spec.Env != "" {
+		fieldsType = reflect.TypeOf(YamlEnvGoal{})
+	}
+	for _, field := range fields {
+		text, err := parseGoalsText(content)
+		if err != nil {
+			return nil, err
+		}
+		key, value, end, err := text.locate(spec.Name, spec.Env)
+		if err != nil {
+			return nil, err
+		}
+		indent := text.indentWidth()
+		if value.Kind != yaml.MappingNode || value.Style&yaml.FlowStyle != 0 || len(value.Content) == 0 {
+			// e.g. `dev: {}`, rewritten as a block mapping
+			if value.Kind != yaml.MappingNode {
+				value = &yaml.Node{Kind: yaml.MappingNode}
+			}
+			value.Style = 0
+			if err := setKey(value, field, fieldsType); err != nil {
+				return nil, err
+			}
+			block, err := encodeBlock(map[string]*yaml.Node{key.Value: value}, indent, key.Column-1)
+			if err != nil {
+				return nil, err
+			}
+			text.replace(key.Line, end, block)
+		} else if err := text.setField(value, end, field, fieldsType, indent); err != nil {
+			return nil, err
+		}
+		content = []byte(text.String())
+	}
+	return content, nil
+}
+
+// locate finds goal name, or its env, returning its key, value and last line
+func (t *goalsText) locate(name string, env string) (*yaml.Node, *yaml.Node, int, error) {
+	i := entry(t.root, name)
+	if i < 0 || reservedKeys[name] {
+		return nil, nil, 0, fmt.Errorf("no such goal: %s", name)
+	}
+	_, end := t.entryLines(t.root, i, len(t.lines))
+	if env == "" {
+		return t.root.Content[i], t.root.Content[i+1], end, nil
+	}
+	envs := mappingValue(t.root.Content[i+1], "envs")
+	j := entry(envs, env)
+	if j < 0 {
+		return nil, nil, 0, fmt.Errorf("no such environment of goal %s: %s", name, env)
+	}
+	if envs.Style&yaml.FlowStyle != 0 {
+		return nil, nil, 0, fmt.Errorf("envs of goal %s are in flow style and could not be edited", name)
+	}
+	_, end = t.entryLines(envs, j, t.envsEnd(i))
+	return envs.Content[j], envs.Content[j+1], end, nil
+}
+
+// setField replaces lines of field in block mapping ending at line end, or inserts them in order of fields of t
+func (t *goalsText) setField(mapping *yaml.Node, end int, field goalField, fieldsType reflect.Type, indent int) error {
+	margin := mapping.Content[0].Column - 1
+	block := ""
+	if field.value != nil {
+		var err error
+		if block, err = encodeBlock(map[string]interface{}{field.key: field.value}, indent, margin); err != nil {
+			return err
+		}
+	}
+	if i := entry(mapping, field.key); i >= 0 {
+		first, last := t.entryLines(mapping, i, end)
+		if comment := withDefault(mapping.Content[i].LineComment, mapping.Content[i+1].LineComment); comment != "" && block != "" {
+			// comment at the end of the first line, e.g. `desc: Plan # shown in list`
+			newline := strings.Index(block, "\n")
+			block = block[:newline] + " " + comment + block[newline:]
+		}
+		t.replace(first, last, block)
+		return nil
+	}
+	if block == "" {
+		return nil
+	}
+	position := fieldPositions(fieldsType)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if next, known := position[key.Value]; known && next > position[field.key] {
+			t.insert(t.headStart(key.Line, key.Column)-1, block)
+			return nil
+		}
+	}
+	_, last := t.entryLines(mapping, len(mapping.Content)-2, end)
+	t.insert(last, block)
+	return nil
+}
+
+// replace puts block instead of lines from first to last
+func (t *goalsText) replace(first int, last int, block string) {
+	t.lines = append(t.lines[:first-1], t.lines[last:]...)
+	if block != "" {
+		t.insert(first-1, block)
+	}
+}
+
+// setKey sets field in mapping node in order of fields of t
+func setKey(mapping *yaml.Node, field goalField, t reflect.Type) error {
+	removeKey(mapping, field.key)
+	if field.value == nil {
+		return nil
+	}
+	var value yaml.Node
+	if err := value.Encode(field.value); err != nil {
+		return err
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.key}, &value)
+	orderKeys(mapping, t)
+	return nil
+}
+
+// encodeBlock renders value as YAML indented by margin spaces
+func encodeBlock(value interface{}, indent int, margin int) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	prefix := strings.Repeat(" ", margin)
+	var block strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			block.WriteString(prefix + line)
+		}
+	}
+	return block.String(), nil
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,324 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const editYaml = `# Project goals
+environments:
+  dev: {}
+  prod: {}
+
+# Terraform
+plan:
+  cmd: terraform
+  args: [ plan ]
+  envs:
+    # development
+    dev:
+      assert:
+        - terraform_workspace: dev   # keep me
+    prod:
+      assert:
+        - terraform_workspace: prod
+  desc: Terraform plan
+
+lint:
+  cmd: golangci-lint # linter
+`
+
+func TestAddGoal(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    GoalSpec
+		want    string
+		wantErr string
+	}{
+		{
+			name: "new goal is appended",
+			spec: GoalSpec{Name: "test", Desc: "Run tests", Cmd: "go", Args: []string{"test", "./..."}},
+			want: editYaml + `
+test:
+  desc: Run tests
+  cmd: go
+  args:
+    - test
+    - ./...
+`,
+		},
+		{
+			name: "new goal on env",
+			spec: GoalSpec{Name: "apply", Env: "dev", Cmd: "terraform", Assert: []YamlAssert{{TerraformWorkspace: "dev"}}},
+			want: editYaml + `
+apply:
+  envs:
+    dev:
+      cmd: terraform
+      assert:
+        - terraform_workspace: dev
+`,
+		},
+		{
+			name: "env is added to existing goal",
+			spec: GoalSpec{Name: "plan", Env: "stage", Cmd: "terraform", Args: []string{"plan"}},
+			want: `# Project goals
+environments:
+  dev: {}
+  prod: {}
+
+# Terraform
+plan:
+  cmd: terraform
+  args: [ plan ]
+  envs:
+    # development
+    dev:
+      assert:
+        - terraform_workspace: dev   # keep me
+    prod:
+      assert:
+        - terraform_workspace: prod
+    stage:
+      cmd: terraform
+      args:
+        - plan
+  desc: Terraform plan
+
+lint:
+  cmd: golangci-lint # linter
+`,
+		},
+		{name: "existing goal", spec: GoalSpec{Name: "lint", Cmd: "go"}, wantErr: "goal lint already exists"},
+		{name: "existing env", spec: GoalSpec{Name: "plan", Env: "dev", Cmd: "go"}, wantErr: "goal plan already exists on dev"},
+		{name: "goal without envs", spec: GoalSpec{Name: "lint", Env: "dev", Cmd: "go"}, wantErr: "goal lint has no envs, remove it first to add it on dev"},
+		{name: "reserved name", spec: GoalSpec{Name: "include", Cmd: "go"}, wantErr: "invalid goal name 'include'"},
+		{name: "empty cmd", spec: GoalSpec{Name: "test"}, wantErr: "test.cmd could not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddGoal([]byte(editYaml), tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("AddGoal() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("AddGoal() diff:\n%s", cmp.Diff(tt.want, string(got)))
+			}
+		})
+	}
+}
+
+func TestEditGoal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		spec    GoalSpec
+		want    string
+		wantErr string
+	}{
+		{
+			name: "command of goal is replaced, args removed",
+			spec: GoalSpec{Name: "plan", Cmd: "tofu"},
+			want: strings.Replace(editYaml, "  cmd: terraform\n  args: [ plan ]\n", "  cmd: tofu\n", 1),
+		},
+		{
+			name: "description is inserted in field order",
+			spec: GoalSpec{Name: "lint", Desc: "Lint code", Cmd: "golangci-lint", Args: []string{"run"}},
+			want: strings.Replace(editYaml, "lint:\n  cmd: golangci-lint # linter\n", "lint:\n  desc: Lint code\n  cmd: golangci-lint # linter\n  args:\n    - run\n", 1),
+		},
+		{
+			name: "assertions of env are replaced keeping comments of other envs",
+			spec: GoalSpec{Name: "plan", Env: "prod", Assert: []YamlAssert{{TerraformWorkspace: "prod"}, {Approval: "yes"}}},
+			want: strings.Replace(editYaml, "        - terraform_workspace: prod\n", "        - terraform_workspace: prod\n        - approval: \"yes\"\n", 1),
+		},
+		{
+			name: "comment at the end of line is kept",
+			spec: GoalSpec{Name: "lint", Desc: "Lint code", Cmd: "golangci-lint"},
+			want: strings.Replace(editYaml, "lint:\n", "lint:\n  desc: Lint code\n", 1),
+		},
+		{
+			name:  "comment of replaced description is kept",
+			input: "plan:\n  desc: Plan # inline\n  cmd: terraform\n",
+			spec:  GoalSpec{Name: "plan", Desc: "new desc"},
+			want:  "plan:\n  desc: new desc # inline\n  cmd: terraform\n",
+		},
+		{
+			name:  "env in flow style is rewritten as block",
+			input: "apply:\n  cmd: terraform\n  envs:\n    dev: {}\n    prod: {}\n",
+			spec:  GoalSpec{Name: "apply", Env: "dev", Desc: "Apply on dev"},
+			want:  "apply:\n  cmd: terraform\n  envs:\n    dev:\n      desc: Apply on dev\n    prod: {}\n",
+		},
+		{name: "unknown goal", spec: GoalSpec{Name: "deploy", Cmd: "make"}, wantErr: "no such goal: deploy"},
+		{name: "unknown env", spec: GoalSpec{Name: "plan", Env: "stage", Cmd: "make"}, wantErr: "no such environment of goal plan: stage"},
+		{name: "nothing to change", spec: GoalSpec{Name: "plan"}, wantErr: "nothing to change in goal plan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			if input == "" {
+				input = editYaml
+			}
+			got, err := EditGoal([]byte(input), tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("EditGoal() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("EditGoal() diff:\n%s", cmp.Diff(tt.want, string(got)))
+			}
+		})
+	}
+}
+
+func TestRemoveGoal(t *testing.T) {
+	tests := []struct {
+		name    string
+		goal    string
+		env     string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "goal with its comment",
+			goal: "plan",
+			want: `# Project goals
+environments:
+  dev: {}
+  prod: {}
+
+lint:
+  cmd: golangci-lint # linter
+`,
+		},
+		{
+			name: "last goal",
+			goal: "lint",
+			want: editYaml[:len(editYaml)-len("\nlint:\n  cmd: golangci-lint # linter\n")],
+		},
+		{
+			name: "env with its comment",
+			goal: "plan",
+			env:  "dev",
+			want: `# Project goals
+environments:
+  dev: {}
+  prod: {}
+
+# Terraform
+plan:
+  cmd: terraform
+  args: [ plan ]
+  envs:
+    prod:
+      assert:
+        - terraform_workspace: prod
+  desc: Terraform plan
+
+lint:
+  cmd: golangci-lint # linter
+`,
+		},
+		{
+			name: "last env of goal",
+			goal: "plan",
+			env:  "prod",
+			want: `# Project goals
+environments:
+  dev: {}
+  prod: {}
+
+# Terraform
+plan:
+  cmd: terraform
+  args: [ plan ]
+  envs:
+    # development
+    dev:
+      assert:
+        - terraform_workspace: dev   # keep me
+  desc: Terraform plan
+
+lint:
+  cmd: golangci-lint # linter
+`,
+		},
+		{name: "no such goal", goal: "apply", wantErr: "no such goal: apply"},
+		{name: "reserved key", goal: "environments", wantErr: "no such goal: environments"},
+		{name: "no such env", goal: "plan", env: "stage", wantErr: "no such environment of goal plan: stage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RemoveGoal([]byte(editYaml), tt.goal, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("RemoveGoal() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RemoveGoal() diff:\n%s", cmp.Diff(tt.want, string(got)))
+			}
+		})
+	}
+}
+
+func TestRemoveGoal_header(t *testing.T) {
+	got, err := RemoveGoal([]byte("# top comment\nplan:\n  cmd: terraform\n\n# linter\nlint:\n  cmd: golangci-lint\n"), "plan", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# top comment\n\n# linter\nlint:\n  cmd: golangci-lint\n"; string(got) != want {
+		t.Errorf("RemoveGoal() = %q, want %q", got, want)
+	}
+}
+
+func TestRemoveGoal_onlyEnv(t *testing.T) {
+	got, err := RemoveGoal([]byte("a:\n  cmd: x\nb:\n  cmd: y\n  envs:\n    dev: {}\n"), "b", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a:\n  cmd: x\n"; string(got) != want {
+		t.Errorf("RemoveGoal() = %q, want %q", got, want)
+	}
+}
+
+func TestParseAssert(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    YamlAssert
+		wantErr bool
+	}{
+		{text: "terraform_workspace: dev", want: YamlAssert{TerraformWorkspace: "dev"}},
+		{text: "{ref: workspace, expect: dev}", want: YamlAssert{Ref: "workspace", Expect: "dev"}},
+		{text: "tool_version: terraform >= 1.3", want: YamlAssert{ToolVersion: &YamlToolVersion{Tool: "terraform", Constraint: ">= 1.3"}}},
+		{text: "{terraform_workspace: dev, kubectl_context: dev}", wantErr: true},
+		{text: "dev", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseAssert(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAssert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !cmp.Equal(got, tt.want) {
+				t.Errorf("ParseAssert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// envColors are colors environments may be highlighted with
+var envColors = map[string]int{
+	"red":     31,
+	"green":   32,
+	"yellow":  33,
+	"blue":    34,
+	"magenta": 35,
+	"cyan":    36,
+}
+
+// Environment is declared once in `environments` and applies to every goal on it
+type Environment struct {
+	Name  string
+	Desc  string
+	Color string
+	// Protected environments require manual approval for every goal
+	Protected bool
+	// Assert is checked before goal assertions
+	Assert []Assertion
+	Source string
+}
+
+// parseEnvironments decodes top-level environments in declaration order
+func parseEnvironments(bytes []byte) ([]Environment, error) {
+	values := map[string]yamlValue{}
+	if err := yaml.Unmarshal(bytes, &values); err != nil {
+		return nil, err
+	}
+	var raw map[string]YamlEnvironment
+	if err := values["environments"].decode(&raw); err != nil {
+		return nil, err
+	}
+	names, err := values["environments"].keys()
+	if err != nil {
+		return nil, err
+	}
+	var environments []Environment
+	for _, name := range names {
+		env := raw[name]
+		if _, known := envColors[env.Color]; env.Color != "" && !known {
+			return nil, fmt.Errorf("environments.%s.color must be one of [%s], actual: '%s'", name, strings.Join(colorNames(), ", "), env.Color)
+		}
+		for idx, assert := range env.Assert {
+			validateAssert(fmt.Sprintf("environments.%s.assert.%d", name, idx), assert)
+		}
+		environments = append(environments, Environment{
+			Name:      name,
+			Desc:      env.Desc,
+			Color:     env.Color,
+			Protected: env.Protected,
+			Assert:    mkAssertions(env.Assert),
+		})
+	}
+	return environments, nil
+}
+
+func colorNames() []string {
+	var names []string
+	for name := range envColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Goals) environment(name string) (*Environment, bool) {
+	for i := range c.Environments {
+		if c.Environments[i].Name == name {
+			return &c.Environments[i], true
+		}
+	}
+	return nil, false
+}
+
+// assertions of goal including the ones of its environment. Goals on protected environments require approval.
+func (c *Goals) assertions(goal Goal) []Assertion {
+	env, declared := c.environment(goal.Env)
+	if goal.Env == "" || !declared || (len(env.Assert) == 0 && !env.Protected) {
+		return goal.Assert
+	}
+	assertions := append(append([]Assertion{}, env.Assert...), goal.Assert...)
+	if env.Protected {
+		for _, assert := range assertions {
+			if _, approval := assert.(ApproveAssertion); approval {
+				return assertions
+			}
+		}
+		assertions = append(assertions, ApproveAssertion{})
+	}
+	return assertions
+}
+
+// validateEnvironments checks that goals use only declared environments, if any are declared
+func (c *Goals) validateEnvironments() error {
+	if len(c.Environments) == 0 {
+		return nil
+	}
+	var names []string
+	for _, env := range c.Environments {
+		names = append(names, env.Name)
+	}
+	for _, goal := range c.Commands {
+		if _, declared := c.environment(goal.Env); goal.Env != "" && !declared {
+			msg := fmt.Sprintf("%s.%s is not declared in environments [%s]", goal.Name, goal.Env, strings.Join(names, ", "))
+			if goal.Source != "" {
+				msg += " of " + goal.Source
+			}
+			return errors.New(msg)
+		}
+	}
+	return nil
+}
+
+// combineEnvironments joins environments of several files, declaring the same environment twice is an error
+func combineEnvironments(all ...*Goals) ([]Environment, error) {
+	declared := map[string]Environment{}
+	var combined []Environment
+	for _, goals := range all {
+		for _, env := range goals.Environments {
+			if existing, exists := declared[env.Name]; exists {
+				return nil, fmt.Errorf("environment %s is declared in both %s and %s", env.Name, existing.Source, env.Source)
+			}
+			declared[env.Name] = env
+			combined = append(combined, env)
+		}
+	}
+	return combined, nil
+}
+
+// colored wraps text into terminal color of environment env, if any
+func (c *Goals) colored(env string, text string) string {
+	if e, declared := c.environment(env); declared {
+		if code, known := envColors[e.Color]; known {
+			return fmt.Sprintf("\x1b[%dm%s\x1b[0m", code, text)
+		}
+	}
+	return text
+}
+
+// RenderEnvironments lists declared environments and goals available on them
+func (c *Goals) RenderEnvironments() {
+	if len(c.Environments) == 0 {
+		Info("No environments declared. Declare them in 'environments' section of goal.yaml")
+		return
+	}
+	Info("Environments:")
+	table := newTable("Environment", "Description", "Protected", "Assertions", "Goals")
+	for _, env := range c.Environments {
+		protected := ""
+		if env.Protected {
+			protected = "🔒 yes"
+		}
+		var assertions []string
+		for idx, assert := range env.Assert {
+			assertions = append(assertions, fmt.Sprintf("%d. %s", idx+1, indent(assert.describe(), "   ")))
+		}
+		var goals []string
+		for _, goal := range c.Commands {
+			if goal.Env == env.Name && !goal.Hidden {
+				goals = append(goals, goal.Name)
+			}
+		}
+		table.Append([]string{c.colored(env.Name, env.Name), env.Desc, protected, strings.Join(assertions, "\n"), strings.Join(goals, "\n")})
+	}
+	table.Render()
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const environmentsYaml = `
+environments:
+  dev:
+    desc: Development
+    color: green
+  prod:
+    desc: Production
+    color: red
+    protected: true
+    assert:
+      - gcloud_project: prod-project
+apply:
+  cmd: terraform
+  envs:
+    dev: {}
+    prod:
+      assert:
+        - terraform_workspace: prod
+`
+
+func TestParseCommands_environments(t *testing.T) {
+	goals, err := ParseCommands([]byte(environmentsYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Environment{
+		{Name: "dev", Desc: "Development", Color: "green"},
+		{Name: "prod", Desc: "Production", Color: "red", Protected: true, Assert: []Assertion{GcloudProjectAssertion{Expect: "prod-project"}}},
+	}
+	if !cmp.Equal(goals.Environments, want) {
+		t.Errorf("ParseCommands() environments diff:\n%s", cmp.Diff(goals.Environments, want))
+	}
+
+	tests := []struct {
+		env  string
+		want []Assertion
+	}{
+		{env: "dev", want: nil},
+		{env: "prod", want: []Assertion{
+			GcloudProjectAssertion{Expect: "prod-project"},
+			TerraformWorkspaceAssertion{Expect: "prod"},
+			ApproveAssertion{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			goal, _ := goals.GetWithEnv("apply", tt.env)
+			if got := goals.assertions(*goal); !cmp.Equal(got, tt.want) {
+				t.Errorf("assertions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommands_undeclaredEnvironment(t *testing.T) {
+	_, err := ParseCommands([]byte(environmentsYaml + "    prdo:\n      cmd: terraform\n"))
+	if err == nil || !strings.Contains(err.Error(), "apply.prdo is not declared in environments [dev, prod]") {
+		t.Errorf("ParseCommands() error = %v, want undeclared environment", err)
+	}
+}
+
+func TestParseCommands_unknownColor(t *testing.T) {
+	_, err := ParseCommands([]byte("environments:\n  dev:\n    color: pink\n"))
+	if err == nil || !strings.Contains(err.Error(), "environments.dev.color") {
+		t.Errorf("ParseCommands() error = %v, want unknown color", err)
+	}
+}
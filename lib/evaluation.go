@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxConcurrentChecks bounds how many assertions are checked at the same time
+const maxConcurrentChecks = 4
+
+// evaluation holds state shared by assertions checked within one run.
+// Identical probes, e.g. two assertions both reading kube context, are executed once.
+type evaluation struct {
+	goals Goals
+	// goal being run, nil when assertions are checked outside of a run
+	goal *Goal
+	// prompt tells whether user may be asked, e.g. for a reason to override schedule. False for `goal check`.
+	prompt bool
+	mu     sync.Mutex
+	memos  map[string]*memoEntry
+}
+
+type memoEntry struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+func newEvaluation(goals Goals) *evaluation {
+	return &evaluation{goals: goals, memos: map[string]*memoEntry{}}
+}
+
+// memo calls fn once per key and returns its result to every caller, including concurrent ones
+func (e *evaluation) memo(key string, fn func() (interface{}, error)) (interface{}, error) {
+	e.mu.Lock()
+	entry, exists := e.memos[key]
+	if !exists {
+		entry = &memoEntry{}
+		e.memos[key] = entry
+	}
+	e.mu.Unlock()
+	entry.once.Do(func() {
+		entry.value, entry.err = fn()
+	})
+	return entry.value, entry.err
+}
+
+// probe is a memoized probe
+func (e *evaluation) probe(name string, args ...string) (string, error) {
+	key := "probe\x00" + name + "\x00" + strings.Join(args, "\x00")
+	out, err := e.memo(key, func() (interface{}, error) {
+		return probe(name, args...)
+	})
+	return out.(string), err
+}
+
+// kubeconfig is a memoized loadKubeconfig
+func (e *evaluation) kubeconfig() (*kubeconfig, error) {
+	config, err := e.memo("kubeconfig", func() (interface{}, error) {
+		return loadKubeconfig()
+	})
+	return config.(*kubeconfig), err
+}
+
+// checkEach checks assertions one by one
+func (e *evaluation) checkEach(assertions []Assertion) []Result {
+	results := make([]Result, len(assertions))
+	for i, assert := range assertions {
+		results[i] = assert.check(e)
+	}
+	return results
+}
+
+// checkConcurrently checks assertions using at most maxConcurrentChecks goroutines.
+// Results are in the same order as assertions.
+func (e *evaluation) checkConcurrently(assertions []Assertion) []Result {
+	results := make([]Result, len(assertions))
+	slots := make(chan struct{}, maxConcurrentChecks)
+	var wg sync.WaitGroup
+	for i, assert := range assertions {
+		wg.Add(1)
+		go func(i int, assert Assertion) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = assert.check(e)
+		}(i, assert)
+	}
+	wg.Wait()
+	return results
+}
+
+// interactiveAssertion is implemented by assertions that may prompt user.
+// Such assertions are checked one by one after all the others.
+type interactiveAssertion interface {
+	interactive() bool
+}
+
+func isInteractive(assert Assertion) bool {
+	if i, ok := assert.(interactiveAssertion); ok {
+		return i.interactive()
+	}
+	return false
+}
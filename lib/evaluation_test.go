@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// probeAssertion reads a shared memoized value and tracks how many checks run at once
+type probeAssertion struct {
+	name    string
+	calls   *int32
+	running *int32
+	peak    *int32
+}
+
+func (a probeAssertion) describe() string {
+	return a.name
+}
+
+func (a probeAssertion) check(e *evaluation) Result {
+	now := atomic.AddInt32(a.running, 1)
+	defer atomic.AddInt32(a.running, -1)
+	for {
+		peak := atomic.LoadInt32(a.peak)
+		if now <= peak || atomic.CompareAndSwapInt32(a.peak, peak, now) {
+			break
+		}
+	}
+	value, _ := e.memo("shared", func() (interface{}, error) {
+		atomic.AddInt32(a.calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "context", nil
+	})
+	return Result{Status: Pass, Actual: a.name + ":" + value.(string)}
+}
+
+func TestEvaluation_checkConcurrently(t *testing.T) {
+	var calls, running, peak int32
+	var assertions []Assertion
+	for i := 0; i < 10; i++ {
+		assertions = append(assertions, probeAssertion{name: fmt.Sprint(i), calls: &calls, running: &running, peak: &peak})
+	}
+
+	results := newEvaluation(Goals{}).checkConcurrently(assertions)
+
+	for i, res := range results {
+		if want := fmt.Sprintf("%d:context", i); res.Actual != want {
+			t.Errorf("results[%d] = %v, want %v", i, res.Actual, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("memoized probe called %d times, want 1", calls)
+	}
+	if peak > maxConcurrentChecks {
+		t.Errorf("%d checks ran concurrently, want at most %d", peak, maxConcurrentChecks)
+	}
+}
+
+func Test_isInteractive(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert Assertion
+		want   bool
+	}{
+		{name: "approval", assert: ApproveAssertion{}, want: true},
+		{name: "kubectl", assert: KubectlContextAssertion{Expect: "dev"}, want: false},
+		{name: "schedule", assert: ScheduleAssertion{}, want: false},
+		{name: "schedule with override", assert: ScheduleAssertion{Override: true}, want: true},
+		{name: "nested", assert: NotAssertion{Assertion: AnyOfAssertion{Assertions: []Assertion{ScheduleAssertion{Override: true}}}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInteractive(tt.assert); got != tt.want {
+				t.Errorf("isInteractive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
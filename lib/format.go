@@ -0,0 +1,284 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// formatIndent is indentation of formatted goals files
+	formatIndent = 2
+	// flowArgsWidth is the longest list of args written in flow style, e.g. `args: [plan, -var-file, vars/dev.tfvars]`
+	flowArgsWidth = 60
+)
+
+// flowLists are keys of lists written in flow style when short
+var flowLists = map[string]bool{"args": true, "args+": true, "aliases": true, "days": true, "allow": true}
+
+// hoistedKeys are moved from envs to goal when every env has the same value
+var hoistedKeys = []string{"desc", "cmd", "args", "dir"}
+
+// FormatGoals normalizes goals file: keys are ordered as in Yaml* types, indentation is 2 spaces, short lists are in
+// flow style and goals are separated by blank lines. Comments and order of goals are kept. With short long forms
+// are converted to short ones, e.g. `tool_version: {tool: terraform, constraint: ">= 1.3"}` to
+// `tool_version: terraform >= 1.3`, and cmd, args, desc and dir same in every env are moved to the goal.
+func FormatGoals(content []byte, short bool) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return content, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("goals file must be a mapping of goal names to goals, got %s", nodeKind(root))
+	}
+	root.Style = 0
+	f := formatter{short: short}
+	reserved, goals := [][]*yaml.Node{}, [][]*yaml.Node{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "include":
+			f.format(value, reflect.TypeOf([]YamlInclude{}), key.Value)
+			reserved = append([][]*yaml.Node{{key, value}}, reserved...)
+		case "environments":
+			f.format(value, reflect.TypeOf(map[string]YamlEnvironment{}), key.Value)
+			reserved = append(reserved, []*yaml.Node{key, value})
+		default:
+			f.format(value, yamlGoalType, key.Value)
+			if short {
+				hoist(value)
+			}
+			goals = append(goals, []*yaml.Node{key, value})
+		}
+	}
+	root.Content = nil
+	for _, pair := range append(reserved, goals...) {
+		root.Content = append(root.Content, pair...)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(formatIndent)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return separateTopLevel(buf.Bytes()), nil
+}
+
+type formatter struct {
+	short bool
+}
+
+// format orders keys of node as fields of t and picks style of lists, recursively
+func (f formatter) format(node *yaml.Node, t reflect.Type, key string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		for _, item := range node.Content {
+			f.format(item, t.Elem(), "")
+		}
+		node.Style = listStyle(node, key)
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			if len(node.Content) > 0 {
+				node.Style = 0
+			}
+			for i := 1; i < len(node.Content); i += 2 {
+				f.format(node.Content[i], t.Elem(), node.Content[i-1].Value)
+			}
+		case reflect.Struct:
+			if len(node.Content) > 0 {
+				node.Style = 0
+			}
+			orderKeys(node, t)
+			fields, _ := yamlFields(t)
+			for i := 1; i < len(node.Content); i += 2 {
+				if field, known := fields[node.Content[i-1].Value]; known {
+					f.format(node.Content[i], field.Type, node.Content[i-1].Value)
+				}
+			}
+			if f.short {
+				shorten(node, t)
+			}
+		}
+	}
+}
+
+// listStyle is flow style for short lists of plain scalars without comments
+func listStyle(node *yaml.Node, key string) yaml.Style {
+	if !flowLists[key] || len(node.Content) == 0 {
+		return 0
+	}
+	var values []string
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			item.HeadComment != "" || item.LineComment != "" || item.FootComment != "" {
+			return 0
+		}
+		values = append(values, item.Value)
+	}
+	if len(strings.Join(values, ", ")) > flowArgsWidth {
+		return 0
+	}
+	return yaml.FlowStyle
+}
+
+// orderKeys sorts keys of mapping node in order of fields of struct t, unknown keys are kept last
+func orderKeys(node *yaml.Node, t reflect.Type) {
+	position := fieldPositions(t)
+	pairs := make([][]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, node.Content[i:i+2])
+	}
+	rank := func(pair []*yaml.Node) int {
+		if i, known := position[pair[0].Value]; known {
+			return i
+		}
+		return t.NumField()
+	}
+	sorted := make([]*yaml.Node, 0, len(node.Content))
+	for len(pairs) > 0 {
+		next := 0
+		for i := range pairs {
+			if rank(pairs[i]) < rank(pairs[next]) {
+				next = i
+			}
+		}
+		sorted = append(sorted, pairs[next]...)
+		pairs = append(pairs[:next], pairs[next+1:]...)
+	}
+	node.Content = sorted
+}
+
+// fieldPositions maps YAML keys of struct t to positions of their fields
+func fieldPositions(t reflect.Type) map[string]int {
+	position := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			position[name] = i
+		}
+	}
+	return position
+}
+
+// shortFields are fields of types with scalar shorthand, long form with only these is shortened
+var shortFields = map[reflect.Type][]string{
+	reflect.TypeOf(YamlToolVersion{}):   {"tool", "constraint"},
+	reflect.TypeOf(YamlKubectlServer{}): {"server"},
+	reflect.TypeOf(YamlInclude{}):       {"file"},
+}
+
+// shorten replaces long form of a type with scalar shorthand with the shorthand, when nothing else is set
+func shorten(node *yaml.Node, t reflect.Type) {
+	fields, ok := shortFields[t]
+	if !ok || len(node.Content) != 2*len(fields) {
+		return
+	}
+	var values []string
+	for _, field := range fields {
+		value := mappingValue(node, field)
+		if value == nil || value.Kind != yaml.ScalarNode || value.Value == "" {
+			return
+		}
+		values = append(values, value.Value)
+	}
+	short := strings.Join(values, " ")
+	if t == reflect.TypeOf(YamlToolVersion{}) && strings.Contains(values[0], " ") {
+		return
+	}
+	*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: short, HeadComment: node.HeadComment,
+		LineComment: node.LineComment, FootComment: node.FootComment, Line: node.Line, Column: node.Column}
+}
+
+// hoist moves keys with the same value in every env of goal to the goal
+func hoist(goal *yaml.Node) {
+	envs := mappingValue(goal, "envs")
+	if envs == nil || envs.Kind != yaml.MappingNode || len(envs.Content) == 0 {
+		return
+	}
+	for _, key := range hoistedKeys {
+		if mappingValue(goal, key) != nil {
+			continue
+		}
+		var shared *yaml.Node
+		same := true
+		for i := 1; i < len(envs.Content) && same; i += 2 {
+			value := mappingValue(envs.Content[i], key)
+			same = value != nil && (shared == nil || sameValue(shared, value))
+			if shared == nil {
+				shared = value
+			}
+		}
+		if !same {
+			continue
+		}
+		hoisted := []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, shared}
+		for i := 1; i < len(envs.Content); i += 2 {
+			removeKey(envs.Content[i], key)
+			if len(envs.Content[i].Content) == 0 {
+				envs.Content[i].Style = yaml.FlowStyle
+			}
+		}
+		goal.Content = append(goal.Content, hoisted...)
+	}
+	orderKeys(goal, yamlGoalType)
+}
+
+func sameValue(a *yaml.Node, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !sameValue(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func removeKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// separateTopLevel puts a blank line before every top-level key but the first, together with its comments
+func separateTopLevel(content []byte) []byte {
+	var out []string
+	seenKey := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if line != "" && !isBlank(line) && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-") {
+			if seenKey {
+				at := len(out)
+				for at > 0 && strings.HasPrefix(out[at-1], "#") {
+					at--
+				}
+				if at > 0 && !isBlank(out[at-1]) {
+					out = append(out[:at], append([]string{"\n"}, out[at:]...)...)
+				}
+			}
+			seenKey = true
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, ""))
+}
@@ -0,0 +1,182 @@
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatGoals(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		short bool
+		want  string
+	}{
+		{
+			name: "key order, indentation and flow args",
+			input: `
+plan:
+    assert:
+    -   terraform_workspace: dev
+    args:
+    - plan
+    - -var-file
+    - vars/dev.tfvars
+    cmd: terraform   # pinned
+    desc: Terraform plan
+lint: {cmd: golangci-lint}
+`,
+			want: `plan:
+  desc: Terraform plan
+  cmd: terraform # pinned
+  args: [plan, -var-file, vars/dev.tfvars]
+  assert:
+    - terraform_workspace: dev
+
+lint:
+  cmd: golangci-lint
+`,
+		},
+		{
+			name: "long args and args with comments are kept in block style",
+			input: `helm:
+  cmd: helm
+  args: [upgrade, release-name, -f, values.yaml, -f, values/dev.yaml, ., --dry-run, --wait, --atomic]
+test:
+  cmd: go
+  args:
+    - test # verbose below
+    - -v
+`,
+			want: `helm:
+  cmd: helm
+  args:
+    - upgrade
+    - release-name
+    - -f
+    - values.yaml
+    - -f
+    - values/dev.yaml
+    - .
+    - --dry-run
+    - --wait
+    - --atomic
+
+test:
+  cmd: go
+  args:
+    - test # verbose below
+    - -v
+`,
+		},
+		{
+			name: "include and environments go first, goals keep order",
+			input: `# goals
+zeta:
+  cmd: z
+
+# shared
+environments:
+  dev: {}
+alpha:
+  cmd: a
+include:
+  - shared.goal.yaml
+`,
+			want: `include:
+  - shared.goal.yaml
+
+# shared
+environments:
+  dev: {}
+
+# goals
+zeta:
+  cmd: z
+
+alpha:
+  cmd: a
+`,
+		},
+		{
+			name:  "short forms",
+			short: true,
+			input: `apply:
+  assert:
+    - tool_version:
+        tool: terraform
+        constraint: ">= 1.3"
+    - tool_version: {tool: helm, constraint: ">= 3", version_cmd: helm version --short}
+  envs:
+    dev:
+      desc: Apply on dev
+      cmd: terraform
+      args: [apply]
+    prod:
+      desc: Apply on prod
+      cmd: terraform
+      args: [apply]
+`,
+			want: `apply:
+  cmd: terraform
+  args: [apply]
+  assert:
+    - tool_version: terraform >= 1.3
+    - tool_version:
+        tool: helm
+        constraint: ">= 3"
+        version_cmd: helm version --short
+  envs:
+    dev:
+      desc: Apply on dev
+    prod:
+      desc: Apply on prod
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatGoals([]byte(tt.input), tt.short)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FormatGoals() diff:\n%s", cmp.Diff(tt.want, string(got)))
+			}
+		})
+	}
+}
+
+// TestFormatGoals_examples checks that formatting is idempotent and does not change goals
+func TestFormatGoals_examples(t *testing.T) {
+	files, _ := filepath.Glob("../examples/*/goal.yaml")
+	for _, file := range files {
+		for _, short := range []bool{false, true} {
+			t.Run(file, func(t *testing.T) {
+				content, err := ioutil.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				formatted, err := FormatGoals(content, short)
+				if err != nil {
+					t.Fatal(err)
+				}
+				again, _ := FormatGoals(formatted, short)
+				if string(again) != string(formatted) {
+					t.Errorf("FormatGoals() is not idempotent:\n%s", cmp.Diff(string(formatted), string(again)))
+				}
+				before, _ := ParseCommands(content)
+				after, err := ParseCommands(formatted)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(before, after) {
+					t.Errorf("FormatGoals() changed goals:\n%s", cmp.Diff(before, after))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// gcloudConfigDir follows gcloud lookup: CLOUDSDK_CONFIG, %APPDATA%\gcloud on Windows, ~/.config/gcloud elsewhere
+func gcloudConfigDir() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "gcloud")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud")
+}
+
+func gcloudActiveConfigName(dir string) string {
+	if name := os.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME"); name != "" {
+		return name
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "active_config")); err == nil {
+		if name := strings.TrimSpace(string(content)); name != "" {
+			return name
+		}
+	}
+	return "default"
+}
+
+// gcloudProperty reads property (e.g. "core/project") of the active gcloud configuration.
+// CLOUDSDK_<SECTION>_<NAME> environment variables take precedence just like in gcloud.
+// found is false when there is no configuration file to read from.
+func gcloudProperty(property string) (value string, found bool) {
+	section, name := "core", property
+	if parts := strings.SplitN(property, "/", 2); len(parts) == 2 {
+		section, name = parts[0], parts[1]
+	}
+	if value := os.Getenv("CLOUDSDK_" + strings.ToUpper(section) + "_" + strings.ToUpper(name)); value != "" {
+		return value, true
+	}
+	dir := gcloudConfigDir()
+	if dir == "" {
+		return "", false
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "configurations", "config_"+gcloudActiveConfigName(dir)))
+	if err != nil {
+		return "", false
+	}
+	return parseIni(content)[section][name], true
+}
+
+// parseIni parses gcloud configuration files: `[section]` headers followed by `key = value` lines
+func parseIni(content []byte) map[string]map[string]string {
+	sections := map[string]map[string]string{}
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			if sections[current] == nil {
+				sections[current] = map[string]string{}
+			}
+			sections[current][strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return sections
+}
+
+// currentGcloudProject reads project of the active gcloud configuration, falls back to
+// `gcloud config get-value project` when configuration file is absent
+func currentGcloudProject(e *evaluation) (string, error) {
+	if project, found := gcloudProperty("core/project"); found {
+		return project, nil
+	}
+	return e.probe("gcloud", "config", "get-value", "project")
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGcloudProperty(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "active_config"), "stage\n")
+	writeFile(t, filepath.Join(dir, "configurations", "config_default"), "[core]\nproject = dev-project\n")
+	writeFile(t, filepath.Join(dir, "configurations", "config_stage"), `[core]
+account = me@example.com
+project = stage-project
+
+[compute]
+zone = us-central1-c
+`)
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	t.Setenv("CLOUDSDK_ACTIVE_CONFIG_NAME", "")
+	t.Setenv("CLOUDSDK_CORE_PROJECT", "")
+
+	tests := []struct {
+		name     string
+		property string
+		env      map[string]string
+		want     string
+	}{
+		{name: "active configuration", property: "core/project", want: "stage-project"},
+		{name: "other section", property: "compute/zone", want: "us-central1-c"},
+		{name: "configuration from env", property: "project", env: map[string]string{"CLOUDSDK_ACTIVE_CONFIG_NAME": "default"}, want: "dev-project"},
+		{name: "property from env", property: "core/project", env: map[string]string{"CLOUDSDK_CORE_PROJECT": "env-project"}, want: "env-project"},
+		{name: "unset property", property: "core/region", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, found := gcloudProperty(tt.property)
+			if !found {
+				t.Fatalf("gcloudProperty() configuration not found")
+			}
+			if got != tt.want {
+				t.Errorf("gcloudProperty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGcloudProperty_absent(t *testing.T) {
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("CLOUDSDK_CORE_PROJECT", "")
+	if _, found := gcloudProperty("core/project"); found {
+		t.Errorf("gcloudProperty() found configuration in empty directory")
+	}
+}
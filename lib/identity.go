@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	osuser "os/user"
+	"regexp"
+	"strings"
+)
+
+// identityProbe resolves current operator identity and tells how to switch it
+type identityProbe struct {
+	Resolve func(e *evaluation) (string, error)
+	// CaseInsensitive identities are compared ignoring case, e.g. emails
+	CaseInsensitive bool
+	Fix             func(allowed []string) string
+	// Executable is the CLI identity is resolved with, if any
+	Executable string
+}
+
+var identities = map[string]identityProbe{
+	"os_user": {
+		Resolve: currentOsUser,
+	},
+	"git_email": {
+		Resolve:         currentGitEmail,
+		Executable:      "git",
+		CaseInsensitive: true,
+		Fix: func(allowed []string) string {
+			return "git config user.email <" + strings.Join(allowed, " | ") + ">"
+		},
+	},
+	"gcloud_account": {
+		Resolve:         currentGcloudAccount,
+		Executable:      "gcloud",
+		CaseInsensitive: true,
+		Fix: func(allowed []string) string {
+			return "gcloud config set account <" + strings.Join(allowed, " | ") + ">"
+		},
+	},
+	"aws_account": {
+		Resolve:    currentAwsIdentity,
+		Executable: "aws",
+		Fix: func(_ []string) string {
+			return "switch AWS_PROFILE or run `aws sso login`"
+		},
+	},
+}
+
+func identityNames() []string {
+	return []string{"os_user", "git_email", "gcloud_account", "aws_account"}
+}
+
+func currentOsUser(_ *evaluation) (string, error) {
+	if current, err := osuser.Current(); err == nil && current.Username != "" {
+		return current.Username, nil
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name, nil
+		}
+	}
+	return "", errors.New("could not determine OS user")
+}
+
+func currentGitEmail(e *evaluation) (string, error) {
+	email, err := e.probe("git", "config", "user.email")
+	var probeErr *ProbeError
+	// `git config` exits with 1 and no output when the key is not set
+	if errors.As(err, &probeErr) && probeErr.ExitCode == 1 && probeErr.Stderr == "" {
+		return "", nil
+	}
+	return email, err
+}
+
+func currentGcloudAccount(e *evaluation) (string, error) {
+	if account, found := gcloudProperty("core/account"); found {
+		return account, nil
+	}
+	return e.probe("gcloud", "config", "get-value", "account")
+}
+
+// currentAwsIdentity returns ARN of the caller, e.g. arn:aws:sts::123456789012:assumed-role/admin/me
+func currentAwsIdentity(e *evaluation) (string, error) {
+	return e.probe("aws", "sts", "get-caller-identity", "--query", "Arn", "--output", "text")
+}
+
+// globMatch matches value against pattern where `*` is any sequence of characters (including `/`)
+// and `?` is a single character
+func globMatch(pattern string, value string, caseInsensitive bool) bool {
+	var re strings.Builder
+	if caseInsensitive {
+		re.WriteString("(?i)")
+	}
+	re.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.MustCompile(re.String()).MatchString(value)
+}
+
+// === IDENTITY
+
+// WhoAssertion checks that operator Identity matches one of Allow values or globs, e.g. "*@platform.example.com"
+type WhoAssertion struct {
+	Identity string
+	Allow    []string
+}
+
+func (a WhoAssertion) describe() string {
+	quoted := make([]string, len(a.Allow))
+	for i, allowed := range a.Allow {
+		quoted[i] = fmt.Sprintf("%q", allowed)
+	}
+	return fmt.Sprintf("%s in [%s]", a.Identity, strings.Join(quoted, ", "))
+}
+
+func (a WhoAssertion) check(e *evaluation) Result {
+	identity, known := identities[a.Identity]
+	if !known {
+		return errored(fmt.Errorf("unknown identity %q", a.Identity))
+	}
+	actual, err := identity.Resolve(e)
+	if err != nil {
+		return errored(err)
+	}
+	expected := strings.Join(a.Allow, " | ")
+	if actual != "" {
+		for _, allowed := range a.Allow {
+			if globMatch(allowed, actual, identity.CaseInsensitive) {
+				return Result{Status: Pass, Expected: expected, Actual: actual}
+			}
+		}
+	}
+	fix := ""
+	if identity.Fix != nil {
+		fix = identity.Fix(a.Allow)
+	}
+	res := failed(expected, actual, fix)
+	if actual == "" {
+		res.Message = a.Identity + " is not set"
+	} else {
+		res.Message = fmt.Sprintf("%s %s is not allowed to run this goal", a.Identity, actual)
+	}
+	return res
+}
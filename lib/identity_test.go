@@ -0,0 +1,53 @@
+package lib
+
+import "testing"
+
+func Test_globMatch(t *testing.T) {
+	tests := []struct {
+		pattern         string
+		value           string
+		caseInsensitive bool
+		want            bool
+	}{
+		{pattern: "*@platform.example.com", value: "jane@platform.example.com", want: true},
+		{pattern: "*@platform.example.com", value: "jane@example.com", want: false},
+		{pattern: "*@platform.example.com", value: "jane@platform.example.com.evil.io", want: false},
+		{pattern: "*@platform.example.com", value: "Jane@Platform.Example.com", caseInsensitive: true, want: true},
+		{pattern: "*@platform.example.com", value: "Jane@Platform.Example.com", want: false},
+		{pattern: "arn:aws:sts::123456789012:assumed-role/admin/*", value: "arn:aws:sts::123456789012:assumed-role/admin/jane", want: true},
+		{pattern: "deployer?", value: "deployer1", want: true},
+		{pattern: "deployer?", value: "deployer", want: false},
+		{pattern: "a.b", value: "axb", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.value, func(t *testing.T) {
+			if got := globMatch(tt.pattern, tt.value, tt.caseInsensitive); got != tt.want {
+				t.Errorf("globMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhoAssertion_check(t *testing.T) {
+	user, err := currentOsUser(nil)
+	if err != nil {
+		t.Skipf("OS user is not available: %s", err)
+	}
+	tests := []struct {
+		name   string
+		assert WhoAssertion
+		want   Status
+	}{
+		{name: "allowed", assert: WhoAssertion{Identity: "os_user", Allow: []string{"nobody-else", user}}, want: Pass},
+		{name: "glob", assert: WhoAssertion{Identity: "os_user", Allow: []string{"*"}}, want: Pass},
+		{name: "not allowed", assert: WhoAssertion{Identity: "os_user", Allow: []string{"nobody-else"}}, want: Fail},
+		{name: "unknown identity", assert: WhoAssertion{Identity: "nickname", Allow: []string{"*"}}, want: Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.assert.check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
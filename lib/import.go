@@ -0,0 +1,297 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ImportSources are tools `goal import` reads goals from
+var ImportSources = []string{"make", "npm", "task"}
+
+// importFiles are files of import sources, the first one found is read
+var importFiles = map[string][]string{
+	"make": {"GNUmakefile", "makefile", "Makefile"},
+	"npm":  {"package.json"},
+	"task": {"Taskfile.yml", "taskfile.yml", "Taskfile.yaml", "taskfile.yaml"},
+}
+
+// FindImportFile returns file of source in dir
+func FindImportFile(dir string, source string) (string, error) {
+	names, known := importFiles[source]
+	if !known {
+		return "", fmt.Errorf("unknown source %s, expected one of [%s]", source, strings.Join(ImportSources, ", "))
+	}
+	for _, name := range names {
+		if path := filepath.Join(dir, name); fileExists(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found in %s", names[len(names)-1], dir)
+}
+
+// ImportGoals reads goals from file of source: phony targets of Makefile, scripts of package.json or tasks of Taskfile.
+// Single-line commands are ported as they are, others (e.g. with prerequisites or variables) run the tool.
+func ImportGoals(source string, file string) ([]GoalSpec, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	switch source {
+	case "make":
+		return importMake(file, content), nil
+	case "npm":
+		return importNpm(filepath.Dir(file))
+	case "task":
+		return importTask(file, content)
+	default:
+		return nil, fmt.Errorf("unknown source %s, expected one of [%s]", source, strings.Join(ImportSources, ", "))
+	}
+}
+
+// AddGoals adds goals to content of goals file, goals already defined there are skipped
+func AddGoals(content []byte, specs []GoalSpec) ([]byte, int, error) {
+	added := 0
+	for _, spec := range specs {
+		text, err := parseGoalsText(content)
+		if err != nil {
+			return nil, 0, err
+		}
+		if reservedKeys[spec.Name] {
+			Warn("⚠️  Skipped goal %s, it is a reserved key", spec.Name)
+			continue
+		}
+		if entry(text.root, spec.Name) >= 0 {
+			Warn("⚠️  Skipped goal %s, it is already defined", spec.Name)
+			continue
+		}
+		if content, err = AddGoal(content, spec); err != nil {
+			return nil, 0, err
+		}
+		added++
+	}
+	return content, added, nil
+}
+
+// RenderGoals renders goals as a new formatted goals file
+func RenderGoals(specs []GoalSpec) ([]byte, error) {
+	content, _, err := AddGoals(nil, specs)
+	if err != nil {
+		return nil, err
+	}
+	return FormatGoals(content, false)
+}
+
+// shellChars need a shell to run command, e.g. pipes, redirects, quotes and globs
+const shellChars = "|&;<>()$`'\"\\*?[]{}~#"
+
+// portCommand splits single-line command into cmd and args, command with shell syntax is run by sh
+func portCommand(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if strings.ContainsAny(line, shellChars) || strings.Contains(fields[0], "=") {
+		return "sh", []string{"-c", line}
+	}
+	return fields[0], fields[1:]
+}
+
+var (
+	makeRule  = regexp.MustCompile(`^([A-Za-z0-9_./%-][A-Za-z0-9_./% \t-]*?)\s*::?((?:[^=].*)?)$`)
+	makeDesc  = regexp.MustCompile(`(?:^|\s)##\s*(.*)$`)
+	makeFiles = map[string]bool{"GNUmakefile": true, "makefile": true, "Makefile": true}
+)
+
+type makeTarget struct {
+	name    string
+	desc    string
+	prereqs []string
+	recipe  []string
+}
+
+// importMake reads phony targets of Makefile, and targets described with `## comment` when none is phony
+func importMake(file string, content []byte) []GoalSpec {
+	var targets []*makeTarget
+	byName := map[string]*makeTarget{}
+	phony := map[string]bool{}
+	var current []*makeTarget
+	var comment []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		for strings.HasSuffix(line, "\\") && !strings.HasPrefix(line, "\t") && scanner.Scan() {
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+		if strings.HasPrefix(line, "\t") {
+			for _, target := range current {
+				target.recipe = append(target.recipe, strings.TrimPrefix(line, "\t"))
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "##") {
+			comment = append(comment, strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+		}
+		if isBlank(line) || isComment(line) {
+			comment = nil
+			continue
+		}
+		current = nil
+		desc := strings.Join(comment, " ")
+		comment = nil
+		if match := makeDesc.FindStringSubmatchIndex(line); match != nil {
+			desc = line[match[2]:match[3]]
+			line = line[:match[0]]
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		rule := makeRule.FindStringSubmatch(line)
+		if rule == nil || strings.Contains(rule[2], "=") {
+			continue
+		}
+		if strings.Contains(rule[2], ";") {
+			// inline recipe, e.g. `clean: ; rm -rf build`
+			continue
+		}
+		prereqs := strings.Fields(rule[2])
+		for _, name := range strings.Fields(rule[1]) {
+			if name == ".PHONY" {
+				for _, prereq := range prereqs {
+					phony[prereq] = true
+				}
+				continue
+			}
+			if strings.HasPrefix(name, ".") || strings.Contains(name, "%") {
+				continue
+			}
+			target, seen := byName[name]
+			if !seen {
+				target = &makeTarget{name: name}
+				byName[name] = target
+				targets = append(targets, target)
+			}
+			if target.desc == "" {
+				target.desc = desc
+			}
+			target.prereqs = append(target.prereqs, prereqs...)
+			current = append(current, target)
+		}
+	}
+	described := len(phony) == 0
+	run := []string{"make"}
+	if !makeFiles[filepath.Base(file)] {
+		run = append(run, "-f", filepath.Base(file))
+	}
+	var specs []GoalSpec
+	for _, target := range targets {
+		if !phony[target.name] && !(described && target.desc != "") {
+			continue
+		}
+		spec := GoalSpec{Name: target.name, Desc: target.desc, Cmd: run[0], Args: append(append([]string{}, run[1:]...), target.name)}
+		if len(target.prereqs) == 0 && len(target.recipe) == 1 {
+			if line := strings.TrimLeft(target.recipe[0], "@"); line != "" && !strings.ContainsAny(line[:1], "-+") &&
+				!strings.Contains(line, "$") && !strings.HasSuffix(line, "\\") {
+				spec.Cmd, spec.Args = portCommand(line)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// importNpm reads scripts of package.json in dir, pre and post scripts are run by package manager with their script
+func importNpm(dir string) ([]GoalSpec, error) {
+	scripts, err := npmScripts(dir)
+	if err != nil {
+		return nil, err
+	}
+	defined := map[string]bool{}
+	for _, name := range mapSliceKeys(scripts) {
+		defined[name] = true
+	}
+	manager := nodePackageManager(dir)
+	var specs []GoalSpec
+	for _, script := range scripts {
+		name := fmt.Sprint(script.Key)
+		if (strings.HasPrefix(name, "pre") && defined[name[3:]]) || (strings.HasPrefix(name, "post") && defined[name[4:]]) {
+			continue
+		}
+		specs = append(specs, GoalSpec{Name: name, Desc: fmt.Sprint(script.Value), Cmd: manager, Args: []string{"run", name}})
+	}
+	return specs, nil
+}
+
+// taskFiles are found by task without --taskfile
+var taskFiles = map[string]bool{"Taskfile.yml": true, "taskfile.yml": true, "Taskfile.yaml": true, "taskfile.yaml": true}
+
+// taskPorted are keys of task which could be ported to a goal, task using other ones (e.g. deps, vars) is run by task
+var taskPorted = map[string]bool{"desc": true, "summary": true, "cmds": true, "cmd": true, "dir": true, "silent": true, "label": true}
+
+// importTask reads tasks of Taskfile, internal ones are skipped
+func importTask(file string, content []byte) ([]GoalSpec, error) {
+	var taskfile struct {
+		Tasks yaml.MapSlice `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(content, &taskfile); err != nil {
+		return nil, err
+	}
+	run := []string{"task"}
+	if base := filepath.Base(file); !taskFiles[base] {
+		run = append(run, "--taskfile", base)
+	}
+	var specs []GoalSpec
+	for _, item := range taskfile.Tasks {
+		name := fmt.Sprint(item.Key)
+		spec := GoalSpec{Name: name, Cmd: run[0], Args: append(append([]string{}, run[1:]...), name)}
+		var cmds []interface{}
+		ported := true
+		switch value := item.Value.(type) {
+		case string:
+			cmds = []interface{}{value}
+		case []interface{}:
+			cmds = value
+		case yaml.MapSlice:
+			for _, field := range value {
+				key := fmt.Sprint(field.Key)
+				switch key {
+				case "internal":
+					if field.Value == true {
+						spec.Name = ""
+					}
+				case "desc":
+					spec.Desc = fmt.Sprint(field.Value)
+				case "summary":
+					if spec.Desc == "" {
+						spec.Desc = strings.SplitN(strings.TrimSpace(fmt.Sprint(field.Value)), "\n", 2)[0]
+					}
+				case "cmds":
+					cmds, _ = field.Value.([]interface{})
+				case "cmd":
+					cmds = []interface{}{field.Value}
+				case "dir":
+					spec.Dir = fmt.Sprint(field.Value)
+				}
+				ported = ported && taskPorted[key]
+			}
+		}
+		if spec.Name == "" {
+			continue
+		}
+		line, simple := "", len(cmds) == 1
+		if simple {
+			line, simple = cmds[0].(string)
+		}
+		if ported && simple && strings.TrimSpace(line) != "" && !strings.Contains(line+spec.Dir, "{{") && !strings.Contains(strings.TrimSpace(line), "\n") {
+			spec.Cmd, spec.Args = portCommand(strings.TrimSpace(line))
+		} else {
+			spec.Dir = ""
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
@@ -0,0 +1,174 @@
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImportGoals(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		file    string
+		content string
+		lock    string
+		want    []GoalSpec
+	}{
+		{
+			name:   "phony targets of Makefile",
+			source: "make",
+			file:   "Makefile",
+			content: `.PHONY: build test lint clean
+BIN := bin/app
+
+## Build the binary
+build:
+	go build -o $(BIN) .
+
+test: ## Run tests
+	@go test ./...
+
+lint: build ## Lint code
+	golangci-lint run
+
+clean:
+	rm -rf bin/*
+
+bin/app: main.go
+	go build -o bin/app .
+`,
+			want: []GoalSpec{
+				{Name: "build", Desc: "Build the binary", Cmd: "make", Args: []string{"build"}},
+				{Name: "test", Desc: "Run tests", Cmd: "go", Args: []string{"test", "./..."}},
+				{Name: "lint", Desc: "Lint code", Cmd: "make", Args: []string{"lint"}},
+				{Name: "clean", Cmd: "sh", Args: []string{"-c", "rm -rf bin/*"}},
+			},
+		},
+		{
+			name:   "described targets of Makefile without phony ones",
+			source: "make",
+			file:   "build.mk",
+			content: `deploy: ## Deploy
+	kubectl apply -f k8s
+	kubectl rollout status deploy/app
+
+out.txt:
+	echo out > out.txt
+`,
+			want: []GoalSpec{
+				{Name: "deploy", Desc: "Deploy", Cmd: "make", Args: []string{"-f", "build.mk", "deploy"}},
+			},
+		},
+		{
+			name:    "scripts of package.json",
+			source:  "npm",
+			file:    "package.json",
+			content: `{"scripts": {"pretest": "eslint .", "test": "jest", "build": "tsc -p ."}}`,
+			lock:    "yarn.lock",
+			want: []GoalSpec{
+				{Name: "test", Desc: "jest", Cmd: "yarn", Args: []string{"run", "test"}},
+				{Name: "build", Desc: "tsc -p .", Cmd: "yarn", Args: []string{"run", "build"}},
+			},
+		},
+		{
+			name:   "tasks of Taskfile",
+			source: "task",
+			file:   "Taskfile.yml",
+			content: `version: '3'
+tasks:
+  fmt: gofmt -w .
+  up:
+    desc: Start services
+    dir: deploy
+    cmds:
+      - docker compose up -d
+  deploy:
+    desc: Deploy
+    dir: deploy
+    deps: [build]
+    cmds: [kubectl apply -f k8s]
+  tag:
+    summary: |
+      Tag a release
+      with details
+    cmds:
+      - git tag {{.VERSION}}
+  helper:
+    internal: true
+    cmds: [echo hi]
+`,
+			want: []GoalSpec{
+				{Name: "fmt", Cmd: "gofmt", Args: []string{"-w", "."}},
+				{Name: "up", Desc: "Start services", Cmd: "docker", Args: []string{"compose", "up", "-d"}, Dir: "deploy"},
+				{Name: "deploy", Desc: "Deploy", Cmd: "task", Args: []string{"deploy"}},
+				{Name: "tag", Desc: "Tag a release", Cmd: "task", Args: []string{"tag"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, tt.file)
+			writeFile(t, file, tt.content)
+			if tt.lock != "" {
+				writeFile(t, filepath.Join(dir, tt.lock), "")
+			}
+			got, err := ImportGoals(tt.source, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("ImportGoals() diff:\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestImportGoals_cli(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Makefile")
+	writeFile(t, file, ".PHONY: lint\nlint:\n\tgolangci-lint run | tee lint.txt\n")
+	specs, err := ImportGoals("make", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := RenderGoals(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goals, err := ParseCommands(rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := goals.Commands[0].Cli(), "sh -c 'golangci-lint run | tee lint.txt'"; got != want {
+		t.Errorf("Cli() = %v, want %v", got, want)
+	}
+}
+
+func TestAddGoals(t *testing.T) {
+	content := `# build goals
+build:
+  cmd: go
+  args: [build, ./...]
+`
+	specs := []GoalSpec{
+		{Name: "build", Cmd: "make", Args: []string{"build"}},
+		{Name: "include", Cmd: "make", Args: []string{"include"}},
+		{Name: "test", Desc: "Run tests", Cmd: "go", Args: []string{"test", "./..."}},
+	}
+	got, added, err := AddGoals([]byte(content), specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := content + `test:
+  desc: Run tests
+  cmd: go
+  args:
+    - test
+    - ./...
+`
+	if added != 1 || string(got) != want {
+		t.Errorf("AddGoals() = %d goal(s), diff:\n%s", added, cmp.Diff(want, string(got)))
+	}
+}
@@ -0,0 +1,165 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// namespaceSeparator joins namespace of included file and goal name, e.g. k8s:apply
+const namespaceSeparator = ":"
+
+// GlobalGoalFile returns path to personal goals available in every directory, $XDG_CONFIG_HOME/goal/goal.yaml or ~/.config/goal/goal.yaml
+func GlobalGoalFile() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "goal", GoalFileName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "goal", GoalFileName)
+}
+
+// LoadFile reads goals file path together with files it includes
+func LoadFile(path string) (*Goals, error) {
+	return loadFile(path, "", false, map[string]bool{})
+}
+
+// LoadGlobalFile reads user-global goals file path together with files it includes. Unlike goals of project files
+// its goals run in current directory (or dir relative to it), as they are shared by every project.
+func LoadGlobalFile(path string) (*Goals, error) {
+	return loadFile(path, "", true, map[string]bool{})
+}
+
+func loadFile(path string, namespace string, global bool, loading map[string]bool) (*Goals, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if loading[abs] {
+		return nil, fmt.Errorf("%s includes itself", path)
+	}
+	loading[abs] = true
+	defer delete(loading, abs)
+
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read goals file %s: %w", path, err)
+	}
+	base := ""
+	if !global {
+		if base, err = filepath.Abs(filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
+	goals, err := parseSource(path, bytes, base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goals file %s: %s", path, err)
+	}
+	includes, err := parseIncludes(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'include' in %s: %s", path, err)
+	}
+	for _, include := range includes {
+		if include.File == "" {
+			return nil, fmt.Errorf("invalid 'include' in %s: 'file' could not be empty", path)
+		}
+		file := include.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		included, err := loadFile(file, include.Namespace, false, loading)
+		if err != nil {
+			return nil, err
+		}
+		if goals, err = CombineGoals(goals, included); err != nil {
+			return nil, err
+		}
+	}
+	if err := goals.validateEnvironments(); err != nil {
+		return nil, err
+	}
+	if namespace != "" {
+		for i := range goals.Environments {
+			goals.Environments[i].Assert = namespaceAll(goals.Environments[i].Assert, namespace)
+		}
+		for i := range goals.Commands {
+			goal := &goals.Commands[i]
+			goal.Name = namespace + namespaceSeparator + goal.Name
+			var aliases []string
+			for _, alias := range goal.Aliases {
+				aliases = append(aliases, namespace+namespaceSeparator+alias)
+			}
+			goal.Aliases = aliases
+			for j, assert := range goal.Assert {
+				goal.Assert[j] = namespaceRefs(assert, namespace)
+			}
+		}
+	}
+	return goals, nil
+}
+
+func parseIncludes(bytes []byte) ([]YamlInclude, error) {
+	values := map[string]yamlValue{}
+	if err := yaml.Unmarshal(bytes, &values); err != nil {
+		return nil, err
+	}
+	var includes []YamlInclude
+	err := values["include"].decode(&includes)
+	return includes, err
+}
+
+// namespaceRefs prefixes goals referenced by assert with namespace, so included files keep referencing their own goals
+func namespaceRefs(assert Assertion, namespace string) Assertion {
+	switch a := assert.(type) {
+	case RefAssertion:
+		a.Ref = namespace + namespaceSeparator + a.Ref
+		return a
+	case AnyOfAssertion:
+		a.Assertions = namespaceAll(a.Assertions, namespace)
+		return a
+	case AllOfAssertion:
+		a.Assertions = namespaceAll(a.Assertions, namespace)
+		return a
+	case NotAssertion:
+		a.Assertion = namespaceRefs(a.Assertion, namespace)
+		return a
+	}
+	return assert
+}
+
+func namespaceAll(assertions []Assertion, namespace string) []Assertion {
+	namespaced := make([]Assertion, len(assertions))
+	for i, assert := range assertions {
+		namespaced[i] = namespaceRefs(assert, namespace)
+	}
+	return namespaced
+}
+
+// CombineGoals joins goals of several files. The same goal on the same environment defined twice is an error.
+func CombineGoals(all ...*Goals) (*Goals, error) {
+	defined := map[string]Goal{}
+	var combined []Goal
+	for _, goals := range all {
+		for _, goal := range goals.Commands {
+			key := goal.Name + "\x00" + goal.Env
+			if existing, exists := defined[key]; exists {
+				return nil, fmt.Errorf("goal %s is defined in both %s and %s", goalTitle(goal), existing.Source, goal.Source)
+			}
+			defined[key] = goal
+			combined = append(combined, goal)
+		}
+	}
+	if err := validateAliases(combined); err != nil {
+		return nil, err
+	}
+	environments, err := combineEnvironments(all...)
+	if err != nil {
+		return nil, err
+	}
+	return &Goals{Commands: orderCommands(combined, environments), Environments: environments}, nil
+}
@@ -0,0 +1,144 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, GoalFileName), `
+include:
+  - shared.goal.yaml
+  - file: ops/k8s.goal.yaml
+    namespace: k8s
+test:
+  cmd: go
+`)
+	writeFile(t, filepath.Join(dir, "shared.goal.yaml"), `
+lint:
+  cmd: golangci-lint
+`)
+	writeFile(t, filepath.Join(dir, "ops", "k8s.goal.yaml"), `
+context:
+  cmd: kubectl
+apply:
+  assert:
+    - ref: context
+      expect: dev
+  cmd: kubectl
+`)
+
+	goals, err := LoadFile(filepath.Join(dir, GoalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, goal := range goals.Commands {
+		names = append(names, goal.Name)
+	}
+	if got, want := strings.Join(names, " "), "test lint k8s:context k8s:apply"; got != want {
+		t.Fatalf("LoadFile() goals = %v, want %v", got, want)
+	}
+	apply := goals.Commands[3]
+	if ref := apply.Assert[0].(RefAssertion).Ref; ref != "k8s:context" {
+		t.Errorf("LoadFile() ref = %v, want k8s:context", ref)
+	}
+	if want := filepath.Join(dir, "ops"); apply.Dir != want {
+		t.Errorf("LoadFile() dir = %v, want %v", apply.Dir, want)
+	}
+}
+
+func TestLoadGlobalFile(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "goal")
+	writeFile(t, filepath.Join(config, GoalFileName), `
+include:
+  - tools.goal.yaml
+pwd:
+  cmd: pwd
+docs:
+  cmd: mkdocs
+  dir: docs
+`)
+	writeFile(t, filepath.Join(config, "tools.goal.yaml"), `
+update:
+  cmd: ./update.sh
+`)
+
+	goals, err := LoadGlobalFile(filepath.Join(config, GoalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwd, _ := goals.get("pwd")
+	var got string
+	inDir(pwd.Dir, func() { got, _ = os.Getwd() })
+	if got != cwd {
+		t.Errorf("global goal runs in %v, want %v", got, cwd)
+	}
+	if docs, _ := goals.get("docs"); docs.Dir != "docs" {
+		t.Errorf("LoadGlobalFile() dir = %v, want docs", docs.Dir)
+	}
+	if update, _ := goals.get("update"); update.Dir != config {
+		t.Errorf("LoadGlobalFile() included dir = %v, want %v", update.Dir, config)
+	}
+}
+
+func TestLoadFile_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name: "duplicate goal",
+			files: map[string]string{
+				GoalFileName:    "include: [ops.goal.yaml]\napply:\n  envs:\n    dev:\n      cmd: kubectl\n",
+				"ops.goal.yaml": "apply:\n  envs:\n    dev:\n      cmd: helm\n",
+			},
+			wantErr: []string{"apply on dev", GoalFileName, "ops.goal.yaml"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				GoalFileName:    "include: [ops.goal.yaml]\n",
+				"ops.goal.yaml": "include: [goal.yaml]\n",
+			},
+			wantErr: []string{"includes itself"},
+		},
+		{
+			name:    "missing include",
+			files:   map[string]string{GoalFileName: "include: [missing.yaml]\n"},
+			wantErr: []string{"failed to read goals file", "missing.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			_, err := LoadFile(filepath.Join(dir, GoalFileName))
+			if err == nil {
+				t.Fatalf("LoadFile() expected error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadFile() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobalGoalFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join("home", "config"))
+	if got, want := GlobalGoalFile(), filepath.Join("home", "config", "goal", GoalFileName); got != want {
+		t.Errorf("GlobalGoalFile() = %v, want %v", got, want)
+	}
+}
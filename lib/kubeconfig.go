@@ -0,0 +1,236 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kubeconfig is the subset of kubectl configuration needed by assertions.
+// Files listed in KUBECONFIG are merged the same way kubectl does: the first file to set a value wins.
+type kubeconfig struct {
+	CurrentContext string             `yaml:"current-context"`
+	Contexts       []kubeNamedContext `yaml:"contexts"`
+	Clusters       []kubeNamedCluster `yaml:"clusters"`
+}
+
+type kubeNamedContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"context"`
+}
+
+type kubeNamedCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+	} `yaml:"cluster"`
+	// dir of the file cluster is defined in, relative certificate-authority paths are resolved against it
+	dir string
+}
+
+var errNoKubeconfig = errors.New("no kubeconfig file found")
+
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// loadKubeconfig reads and merges kubeconfig files. errNoKubeconfig is returned when none of them exists.
+func loadKubeconfig() (*kubeconfig, error) {
+	merged := &kubeconfig{}
+	found := false
+	contexts := map[string]bool{}
+	clusters := map[string]bool{}
+	for _, path := range kubeconfigPaths() {
+		if path == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		var file kubeconfig
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig %s: %s", path, err)
+		}
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = file.CurrentContext
+		}
+		for _, context := range file.Contexts {
+			if !contexts[context.Name] {
+				contexts[context.Name] = true
+				merged.Contexts = append(merged.Contexts, context)
+			}
+		}
+		for _, cluster := range file.Clusters {
+			if !clusters[cluster.Name] {
+				clusters[cluster.Name] = true
+				cluster.dir = filepath.Dir(path)
+				merged.Clusters = append(merged.Clusters, cluster)
+			}
+		}
+	}
+	if !found {
+		return nil, errNoKubeconfig
+	}
+	return merged, nil
+}
+
+func (k *kubeconfig) currentContext() *kubeNamedContext {
+	for i := range k.Contexts {
+		if k.Contexts[i].Name == k.CurrentContext {
+			return &k.Contexts[i]
+		}
+	}
+	return nil
+}
+
+func (k *kubeconfig) currentCluster() *kubeNamedCluster {
+	context := k.currentContext()
+	if context == nil {
+		return nil
+	}
+	for i := range k.Clusters {
+		if k.Clusters[i].Name == context.Context.Cluster {
+			return &k.Clusters[i]
+		}
+	}
+	return nil
+}
+
+func (c *kubeNamedCluster) caPem() ([]byte, error) {
+	if c.Cluster.CertificateAuthorityData != "" {
+		decoded, err := base64.StdEncoding.DecodeString(c.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate-authority-data: %s", err)
+		}
+		return decoded, nil
+	}
+	if c.Cluster.CertificateAuthority != "" {
+		path := c.Cluster.CertificateAuthority
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.dir, path)
+		}
+		return ioutil.ReadFile(path)
+	}
+	return nil, errors.New("current cluster has no certificate authority")
+}
+
+// currentKubeContext reads current context from kubeconfig, falls back to `kubectl` when there is no kubeconfig file
+func currentKubeContext(e *evaluation) (string, error) {
+	config, err := e.kubeconfig()
+	if err == errNoKubeconfig {
+		return e.probe("kubectl", "config", "current-context")
+	}
+	if err != nil {
+		return "", err
+	}
+	return config.CurrentContext, nil
+}
+
+// currentKubeNamespace returns namespace of the current context, "default" when it is not set
+func currentKubeNamespace(e *evaluation) (string, error) {
+	var namespace string
+	config, err := e.kubeconfig()
+	if err == errNoKubeconfig {
+		if namespace, err = e.probe("kubectl", "config", "view", "--minify", "-o", "jsonpath={..namespace}"); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	} else if context := config.currentContext(); context != nil {
+		namespace = context.Context.Namespace
+	}
+	if namespace == "" {
+		return "default", nil
+	}
+	return namespace, nil
+}
+
+// currentKubeServer returns API server URL of the current context's cluster
+func currentKubeServer(e *evaluation) (string, error) {
+	config, err := e.kubeconfig()
+	if err == errNoKubeconfig {
+		return e.probe("kubectl", "config", "view", "--minify", "-o", "jsonpath={.clusters[0].cluster.server}")
+	}
+	if err != nil {
+		return "", err
+	}
+	if cluster := config.currentCluster(); cluster != nil {
+		return cluster.Cluster.Server, nil
+	}
+	return "", nil
+}
+
+// currentKubeCaFingerprint returns SHA-256 fingerprint of the current context's cluster CA
+func currentKubeCaFingerprint(e *evaluation) (string, error) {
+	config, err := e.kubeconfig()
+	if err == errNoKubeconfig {
+		return currentKubeCaFingerprintFromCli(e)
+	}
+	if err != nil {
+		return "", err
+	}
+	cluster := config.currentCluster()
+	if cluster == nil {
+		return "", fmt.Errorf("cluster of context %s not found", config.CurrentContext)
+	}
+	caPem, err := cluster.caPem()
+	if err != nil {
+		return "", err
+	}
+	return caFingerprint(caPem)
+}
+
+func currentKubeCaFingerprintFromCli(e *evaluation) (string, error) {
+	data, err := e.probe("kubectl", "config", "view", "--minify", "--raw", "-o", "jsonpath={.clusters[0].cluster.certificate-authority-data}")
+	if err != nil {
+		return "", err
+	}
+	if data == "" {
+		return "", errors.New("current cluster has no certificate-authority-data")
+	}
+	caPem, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid certificate-authority-data: %s", err)
+	}
+	return caFingerprint(caPem)
+}
+
+// caFingerprint returns lowercase hex SHA-256 of the first PEM certificate, same as
+// `openssl x509 -noout -fingerprint -sha256` without colons
+func caFingerprint(caPem []byte) (string, error) {
+	block, _ := pem.Decode(caPem)
+	if block == nil {
+		return "", errors.New("certificate authority is not a PEM certificate")
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
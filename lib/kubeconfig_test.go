@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKubeconfig_merge(t *testing.T) {
+	dir := t.TempDir()
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("stage-ca")})
+	writeFile(t, filepath.Join(dir, "stage-ca.crt"), string(caPem))
+	writeFile(t, filepath.Join(dir, "current"), `
+current-context: stage
+contexts:
+  - name: stage
+    context:
+      cluster: stage-cluster
+      namespace: payments
+`)
+	writeFile(t, filepath.Join(dir, "clusters"), `
+current-context: prod
+contexts:
+  - name: stage
+    context:
+      cluster: prod-cluster
+  - name: prod
+    context:
+      cluster: prod-cluster
+clusters:
+  - name: stage-cluster
+    cluster:
+      server: https://10.0.0.2
+      certificate-authority: stage-ca.crt
+  - name: prod-cluster
+    cluster:
+      server: https://10.0.0.3
+      certificate-authority-data: `+base64.StdEncoding.EncodeToString([]byte("garbage"))+`
+`)
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "missing")+string(os.PathListSeparator)+
+		filepath.Join(dir, "current")+string(os.PathListSeparator)+
+		filepath.Join(dir, "clusters"))
+
+	e := newEvaluation(Goals{})
+	if got, err := currentKubeContext(e); err != nil || got != "stage" {
+		t.Errorf("currentKubeContext() = %v, %v, want %v", got, err, "stage")
+	}
+	if got, err := currentKubeNamespace(e); err != nil || got != "payments" {
+		t.Errorf("currentKubeNamespace() = %v, %v, want %v", got, err, "payments")
+	}
+	if got, err := currentKubeServer(e); err != nil || got != "https://10.0.0.2" {
+		t.Errorf("currentKubeServer() = %v, %v, want %v", got, err, "https://10.0.0.2")
+	}
+	want, _ := caFingerprint(caPem)
+	if got, err := currentKubeCaFingerprint(e); err != nil || got != want {
+		t.Errorf("currentKubeCaFingerprint() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestLoadKubeconfig_absent(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	if _, err := loadKubeconfig(); err != errNoKubeconfig {
+		t.Errorf("loadKubeconfig() error = %v, want %v", err, errNoKubeconfig)
+	}
+}
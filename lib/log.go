@@ -11,7 +11,13 @@ func Fatal(message string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// Warn prints message to stderr without exiting
+func Warn(message string, args ...interface{}) {
+	msg := fmt.Sprintf(message, args...)
+	_, _ = os.Stderr.WriteString(msg + "\n")
+}
+
 func Info(message string, args ...interface{}) {
 	line := fmt.Sprintf(message, args...)
-	fmt.Printf(line + "\n")
+	fmt.Println(line)
 }
@@ -0,0 +1,243 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// pluginPrefix of executables implementing assertions that are not built in, e.g. goal-assert-vault_token
+const pluginPrefix = "goal-assert-"
+
+// pluginDir is checked before PATH in current directory and its parents up to the repository root
+var pluginDir = filepath.Join(".goal", "plugins")
+
+// pluginDirs returns existing plugin directories, nearest first
+func pluginDirs() []string {
+	var dirs []string
+	for _, dir := range parentDirs(".") {
+		dir = filepath.Join(dir, pluginDir)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// pluginRequest is written as JSON to plugin stdin
+type pluginRequest struct {
+	// Action is "check"
+	Action string      `json:"action"`
+	Name   string      `json:"name"`
+	Config interface{} `json:"config"`
+	Goal   string      `json:"goal,omitempty"`
+	Env    string      `json:"env,omitempty"`
+}
+
+// pluginResponse is read as JSON from plugin stdout
+type pluginResponse struct {
+	Ok       bool   `json:"ok"`
+	Actual   string `json:"actual"`
+	Expected string `json:"expected"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+	// Error means the check could not be performed
+	Error string `json:"error"`
+}
+
+func pluginExecutable(name string) string {
+	if runtime.GOOS == "windows" {
+		return pluginPrefix + name + ".exe"
+	}
+	return pluginPrefix + name
+}
+
+// findPlugin returns path to the plugin implementing assertion name, empty when there is none
+func findPlugin(name string) string {
+	for _, dir := range pluginDirs() {
+		local := filepath.Join(dir, pluginExecutable(name))
+		if info, err := os.Stat(local); err == nil && !info.IsDir() {
+			return local
+		}
+	}
+	if path, err := osexec.LookPath(pluginExecutable(name)); err == nil {
+		return path
+	}
+	return ""
+}
+
+// discoverPlugins lists names of assertions provided by plugins in .goal/plugins and PATH
+func discoverPlugins() []string {
+	seen := map[string]bool{}
+	dirs := append(pluginDirs(), filepath.SplitList(os.Getenv("PATH"))...)
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(name, pluginPrefix) {
+				continue
+			}
+			name = strings.TrimSuffix(strings.TrimPrefix(name, pluginPrefix), ".exe")
+			if name != "" {
+				seen[name] = true
+			}
+		}
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// assertionKinds lists built-in assertions followed by the ones provided by plugins
+func assertionKinds() []string {
+	return append(append([]string{}, availableAssertions...), discoverPlugins()...)
+}
+
+// jsonCompatible converts YAML maps with interface{} keys to maps with string keys
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := map[string]interface{}{}
+		for key, item := range v {
+			converted[key] = jsonCompatible(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = jsonCompatible(item)
+		}
+		return converted
+	default:
+		return v
+	}
+}
+
+// === PLUGINS
+
+// PluginAssertion delegates to executable Path speaking JSON over stdin/stdout.
+// See pluginRequest and pluginResponse for the protocol. Path is looked up when the assertion is checked,
+// so plugins of a repository are not found (and run) by merely listing or validating its goals.
+type PluginAssertion struct {
+	Name   string
+	Config interface{}
+	Path   string
+	// Desc is `desc` of the assertion entry, shown instead of the name and config
+	Desc string
+}
+
+// describe does not run plugin, goals are listed without running code of plugins
+func (a PluginAssertion) describe() string {
+	if a.Desc != "" {
+		return a.Desc
+	}
+	config, _ := json.Marshal(a.Config)
+	return fmt.Sprintf("%s: %s", a.Name, config)
+}
+
+func (a PluginAssertion) check(e *evaluation) Result {
+	request := pluginRequest{Action: "check", Name: a.Name, Config: a.Config}
+	if e.goal != nil {
+		request.Goal, request.Env = e.goal.Name, e.goal.Env
+	}
+	if a.Path == "" {
+		a.Path = findPlugin(a.Name)
+	}
+	if a.Path == "" {
+		return a.missing()
+	}
+	res, err := a.call(request)
+	if err != nil {
+		return errored(err)
+	}
+	if res.Error != "" {
+		return Result{Status: Error, Err: errors.New(res.Error), Fix: res.Fix}
+	}
+	status := Fail
+	if res.Ok {
+		status = Pass
+	}
+	return Result{
+		Status:   status,
+		Expected: res.Expected,
+		Actual:   res.Actual,
+		Message:  res.Message,
+		Fix:      res.Fix,
+	}
+}
+
+// unknownAssertion describes assertion name which is neither built in nor one of plugins, e.g. a typo in a built-in assertion.
+// It is empty for known assertions.
+func unknownAssertion(name string, plugins []string) string {
+	if contains(availableAssertions, name) || contains(plugins, name) {
+		return ""
+	}
+	msg := fmt.Sprintf("unknown assertion '%s': neither built-in [%s] nor %s found in %s or PATH",
+		name, strings.Join(availableAssertions, ", "), pluginExecutable(name), pluginDir)
+	if suggestions := closest(name, append(append([]string{}, availableAssertions...), plugins...)); len(suggestions) > 0 {
+		msg += fmt.Sprintf(". Did you mean %s?", strings.Join(suggestions, ", "))
+	}
+	return msg
+}
+
+// missing reports plugin which was removed after goals were loaded
+func (a PluginAssertion) missing() Result {
+	return Result{
+		Status: Error,
+		Err:    errors.New(unknownAssertion(a.Name, nil)),
+		Fix:    fmt.Sprintf("install %s to %s or PATH", pluginExecutable(a.Name), pluginDir),
+	}
+}
+
+func (a PluginAssertion) call(request pluginRequest) (*pluginResponse, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	cmd := osexec.Command(a.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		probeErr := &ProbeError{
+			Executable: pluginExecutable(a.Name),
+			Path:       a.Path,
+			Cli:        a.Path,
+			ExitCode:   -1,
+			Stderr:     strings.TrimSpace(stderr.String()),
+		}
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			probeErr.ExitCode = exitErr.ExitCode()
+		} else if probeErr.Stderr == "" {
+			probeErr.Stderr = err.Error()
+		}
+		return nil, probeErr
+	}
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %s", a.Path, err)
+	}
+	return &response, nil
+}
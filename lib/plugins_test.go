@@ -0,0 +1,124 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// vault_token plugin passes when config ttl equals "1h" and echoes goal and env in actual
+const testPlugin = `#!/bin/sh
+request=$(cat)
+case "$request" in
+  *'"ttl":"1h"'*) echo '{"ok":true}' ;;
+  *'"ttl":"broken"'*) echo 'not json' ;;
+  *'"ttl":"error"'*) echo '{"error":"vault is sealed"}' ;;
+  *'"ttl":"crash"'*) echo 'boom' >&2; exit 3 ;;
+  *) goal=$(echo "$request" | sed 's/.*"goal":"\([^"]*\)".*/\1/')
+     env=$(echo "$request" | sed 's/.*"env":"\([^"]*\)".*/\1/')
+     echo "{\"ok\":false,\"expected\":\"ttl 1h\",\"actual\":\"$goal/$env\",\"fix\":\"vault login\"}" ;;
+esac
+`
+
+// installTestPlugin puts vault_token plugin to a temporary directory prepended to PATH and returns the directory
+func installTestPlugin(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script requires sh")
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "goal-assert-vault_token"), testPlugin)
+	if err := os.Chmod(filepath.Join(dir, "goal-assert-vault_token"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func TestPluginAssertion(t *testing.T) {
+	installTestPlugin(t)
+	tests := []struct {
+		name       string
+		ttl        string
+		want       Status
+		wantActual string
+		wantErr    string
+	}{
+		{name: "pass", ttl: "1h", want: Pass},
+		{name: "fail", ttl: "5m", want: Fail, wantActual: "apply/prod"},
+		{name: "error reported by plugin", ttl: "error", want: Error, wantErr: "vault is sealed"},
+		{name: "invalid response", ttl: "broken", want: Error, wantErr: "invalid response"},
+		{name: "non-zero exit", ttl: "crash", want: Error, wantErr: "exited with code 3: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := PluginAssertion{
+				Name:   "vault_token",
+				Config: jsonCompatible(map[interface{}]interface{}{"ttl": tt.ttl}),
+			}
+			e := newEvaluation(Goals{})
+			e.goal = &Goal{Name: "apply", Env: "prod"}
+			res := a.check(e)
+			if res.Status != tt.want {
+				t.Fatalf("check() = %v, want %v (%v)", res.Status, tt.want, res.Err)
+			}
+			if res.Actual != tt.wantActual {
+				t.Errorf("check() actual = %v, want %v", res.Actual, tt.wantActual)
+			}
+			if tt.wantErr != "" && (res.Err == nil || !strings.Contains(res.Err.Error(), tt.wantErr)) {
+				t.Errorf("check() error = %v, want %v", res.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginAssertion_describe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script requires sh")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	plugin := filepath.Join(dir, "goal-assert-vault_token")
+	writeFile(t, plugin, "#!/bin/sh\ntouch "+marker+"\necho '{\"ok\":true}'\n")
+	if err := os.Chmod(plugin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	a := PluginAssertion{Name: "vault_token", Config: jsonCompatible(map[interface{}]interface{}{"ttl": "1h"}), Path: plugin}
+	if got := a.describe(); got != `vault_token: {"ttl":"1h"}` {
+		t.Errorf("describe() = %v", got)
+	}
+	a.Desc = "Vault token is valid for an hour"
+	if got := a.describe(); got != a.Desc {
+		t.Errorf("describe() = %v, want %v", got, a.Desc)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("describe() ran the plugin")
+	}
+}
+
+func TestPluginAssertion_missing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	a := PluginAssertion{Name: "kubectl_contex", Config: "dev"}
+	res := a.check(newEvaluation(Goals{}))
+	if res.Status != Error {
+		t.Fatalf("check() = %v, want %v", res.Status, Error)
+	}
+	if want := "Did you mean kubectl_context"; res.Err == nil || !strings.Contains(res.Err.Error(), want) {
+		t.Errorf("check() error = %v, want %v", res.Err, want)
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	// only the test plugin, other goal-assert-* executables may be installed
+	t.Setenv("PATH", installTestPlugin(t))
+	if got := discoverPlugins(); len(got) != 1 || got[0] != "vault_token" {
+		t.Errorf("discoverPlugins() = %v, want [vault_token]", got)
+	}
+	if got := findPlugin("unknown"); got != "" {
+		t.Errorf("findPlugin() = %v, want none", got)
+	}
+	if got := assertionKinds(); got[len(got)-1] != "vault_token" {
+		t.Errorf("assertionKinds() = %v, want plugins last", got)
+	}
+}
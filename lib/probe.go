@@ -0,0 +1,62 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"strings"
+)
+
+// ProbeError describes why a CLI used by an assertion could not produce its output
+type ProbeError struct {
+	// Executable is the name looked up in PATH
+	Executable string
+	// Path is the resolved executable, empty when it was not found
+	Path     string
+	Cli      string
+	ExitCode int
+	Stderr   string
+	NotFound bool
+}
+
+func (e *ProbeError) Error() string {
+	if e.NotFound {
+		return fmt.Sprintf("%s not found in PATH", e.Executable)
+	}
+	msg := fmt.Sprintf("`%s` exited with code %d", e.Cli, e.ExitCode)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+// probe runs a CLI and returns its trimmed stdout. Any failure to run it is reported as *ProbeError.
+func probe(name string, args ...string) (string, error) {
+	cli := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	path, err := osexec.LookPath(name)
+	if err != nil {
+		return "", &ProbeError{Executable: name, Cli: cli, ExitCode: -1, NotFound: true}
+	}
+	cmd := osexec.Command(path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		probeErr := &ProbeError{
+			Executable: name,
+			Path:       path,
+			Cli:        cli,
+			ExitCode:   -1,
+			Stderr:     strings.TrimSpace(stderr.String()),
+		}
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			probeErr.ExitCode = exitErr.ExitCode()
+		} else if probeErr.Stderr == "" {
+			probeErr.Stderr = err.Error()
+		}
+		return "", probeErr
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
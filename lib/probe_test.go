@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	if out, err := probe("go", "env", "GOOS"); err != nil || out == "" {
+		t.Errorf("probe() = %v, %v, want trimmed output", out, err)
+	}
+
+	_, err := probe("goal-definitely-not-installed", "version")
+	var notFound *ProbeError
+	if !errors.As(err, &notFound) || !notFound.NotFound {
+		t.Fatalf("probe() error = %v, want not found ProbeError", err)
+	}
+	if got := notFound.Error(); got != "goal-definitely-not-installed not found in PATH" {
+		t.Errorf("Error() = %v", got)
+	}
+
+	_, err = probe("go", "no-such-subcommand")
+	var exited *ProbeError
+	if !errors.As(err, &exited) || exited.NotFound {
+		t.Fatalf("probe() error = %v, want exit ProbeError", err)
+	}
+	if exited.ExitCode == 0 || exited.Path == "" || !strings.Contains(exited.Stderr, "no-such-subcommand") {
+		t.Errorf("probe() error = %#v, want exit code, resolved path and stderr", exited)
+	}
+}
+
+func TestResult_render(t *testing.T) {
+	err := &ProbeError{Executable: "kubectl", NotFound: true}
+	tests := []struct {
+		name   string
+		result Result
+		want   []string
+	}{
+		{
+			name:   "fail",
+			result: failed("dev", "", "terraform workspace select dev"),
+			want:   []string{"❌ Precondition failed: ws", "Expected: dev", `Actual:   ""`, "Fix:      terraform workspace select dev"},
+		},
+		{
+			name:   "error",
+			result: errored(err),
+			want:   []string{"could not be checked: ws", "kubectl not found in PATH"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.result.render("ws")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("render() = %v, want to contain %v", got, want)
+				}
+			}
+		})
+	}
+}
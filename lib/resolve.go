@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is how many closest names are suggested for a typo
+const maxSuggestions = 3
+
+// Resolve finds goal by name, alias or unique prefix of either and its env by exact name, so a slip never picks
+// another (e.g. production) environment. When nothing matches the error suggests the closest names.
+func (c *Goals) Resolve(name string, env string) (*Goal, error) {
+	resolved, err := c.ResolveName(name)
+	if err != nil {
+		return nil, err
+	}
+	envs := c.Envs(resolved)
+	if len(envs) == 0 || (len(envs) == 1 && envs[0] == "") {
+		if env != "" {
+			return nil, fmt.Errorf("goal %s has no environments, remove --on %s", resolved, env)
+		}
+		goal, _ := c.GetWithEnv(resolved, "")
+		return goal, nil
+	}
+	if env == "" {
+		return nil, fmt.Errorf("goal %s requires environment, specify one of [%s] with --on", resolved, strings.Join(envs, ", "))
+	}
+	if !contains(envs, env) {
+		msg := fmt.Sprintf("no such environment of goal %s: %s.", resolved, env)
+		var suggestions []string
+		for _, candidate := range envs {
+			if strings.HasPrefix(candidate, env) {
+				suggestions = append(suggestions, candidate)
+			}
+		}
+		for _, candidate := range closest(env, envs) {
+			if !contains(suggestions, candidate) {
+				suggestions = append(suggestions, candidate)
+			}
+		}
+		if len(suggestions) > maxSuggestions {
+			suggestions = suggestions[:maxSuggestions]
+		}
+		if len(suggestions) > 0 {
+			msg += fmt.Sprintf(" Did you mean %s?", strings.Join(suggestions, ", "))
+		}
+		return nil, fmt.Errorf("%s Available environments: [%s]", msg, strings.Join(envs, ", "))
+	}
+	goal, _ := c.GetWithEnv(resolved, env)
+	return goal, nil
+}
+
+// ResolveName returns name of goal matching name, one of its aliases or their unique prefix.
+// Hidden goals match only by exact name or alias.
+func (c *Goals) ResolveName(name string) (string, error) {
+	for _, goal := range c.Commands {
+		if goal.Hidden && (goal.Name == name || contains(goal.Aliases, name)) {
+			return goal.Name, nil
+		}
+	}
+	var names []string
+	aliases := map[string]string{}
+	for _, goal := range c.WithoutHidden().Commands {
+		if _, seen := aliases[goal.Name]; !seen {
+			names = append(names, goal.Name)
+			aliases[goal.Name] = goal.Name
+		}
+		for _, alias := range goal.Aliases {
+			if _, seen := aliases[alias]; !seen {
+				names = append(names, alias)
+				aliases[alias] = goal.Name
+			}
+		}
+	}
+	return match("goal", name, names, aliases)
+}
+
+// Envs of goal name in listing order, a single empty env for goals without environments
+func (c *Goals) Envs(name string) []string {
+	var envs []string
+	for _, goal := range c.Commands {
+		if goal.Name == name {
+			envs = append(envs, goal.Env)
+		}
+	}
+	return envs
+}
+
+// match finds value among candidates exactly or by unique prefix. aliases maps candidates to what they stand for.
+func match(kind string, value string, candidates []string, aliases map[string]string) (string, error) {
+	target := func(candidate string) string {
+		if resolved, aliased := aliases[candidate]; aliased {
+			return resolved
+		}
+		return candidate
+	}
+	for _, candidate := range candidates {
+		if candidate == value {
+			return target(candidate), nil
+		}
+	}
+	var matches []string
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if value != "" && strings.HasPrefix(candidate, value) && !seen[target(candidate)] {
+			seen[target(candidate)] = true
+			matches = append(matches, target(candidate))
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		msg := fmt.Sprintf("no such %s: %s.", kind, value)
+		if suggestions := closest(value, candidates); len(suggestions) > 0 {
+			msg += fmt.Sprintf(" Did you mean %s?", strings.Join(suggestions, ", "))
+		}
+		return "", errors.New(msg)
+	default:
+		return "", fmt.Errorf("%s %s is ambiguous, could be one of [%s].", kind, value, strings.Join(matches, ", "))
+	}
+}
+
+// closest returns candidates within a few edits of value, the closest first
+func closest(value string, candidates []string) []string {
+	threshold := len(value)/3 + 1
+	distances := map[string]int{}
+	var suggestions []string
+	for _, candidate := range candidates {
+		if d := editDistance(value, candidate); d <= threshold {
+			distances[candidate] = d
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// editDistance is Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
+
+// validateAliases checks that every alias names a single goal and does not shadow goal names
+func validateAliases(goals []Goal) error {
+	names := map[string]bool{}
+	for _, goal := range goals {
+		names[goal.Name] = true
+	}
+	owners := map[string]string{}
+	for _, goal := range goals {
+		for _, alias := range goal.Aliases {
+			if names[alias] {
+				return fmt.Errorf("alias %s of goal %s is a name of another goal", alias, goal.Name)
+			}
+			if owner, exists := owners[alias]; exists && owner != goal.Name {
+				return fmt.Errorf("alias %s is used by both %s and %s", alias, owner, goal.Name)
+			}
+			owners[alias] = goal.Name
+		}
+	}
+	return nil
+}
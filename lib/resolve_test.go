@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestGoals_Resolve(t *testing.T) {
+	goals := &Goals{Commands: []Goal{
+		{Name: "terraform-apply", Aliases: []string{"tfa"}, Env: "dev"},
+		{Name: "terraform-apply", Aliases: []string{"tfa"}, Env: "prod"},
+		{Name: "terraform-plan", Env: "dev"},
+		{Name: "test"},
+		{Name: "lint"},
+		{Name: "tf-workspace", Hidden: true},
+	}}
+	tests := []struct {
+		name    string
+		goal    string
+		env     string
+		want    string
+		wantErr string
+	}{
+		{name: "exact", goal: "test", want: "test"},
+		{name: "alias", goal: "tfa", env: "prod", want: "terraform-apply on prod"},
+		{name: "unique prefix", goal: "terraform-p", env: "dev", want: "terraform-plan on dev"},
+		{name: "env prefix", goal: "terraform-apply", env: "pr", wantErr: "no such environment of goal terraform-apply: pr. Did you mean prod? Available environments: [dev, prod]"},
+		{name: "exact wins over prefix", goal: "test", want: "test"},
+		{name: "hidden by name", goal: "tf-workspace", want: "tf-workspace"},
+		{name: "hidden not by prefix", goal: "tf-work", wantErr: "no such goal: tf-work."},
+		{name: "ambiguous prefix", goal: "te", wantErr: "goal te is ambiguous, could be one of [terraform-apply, terraform-plan, test]."},
+		{name: "typo", goal: "lnit", wantErr: "no such goal: lnit. Did you mean lint?"},
+		{name: "nothing close", goal: "deploy", wantErr: "no such goal: deploy."},
+		{name: "env typo", goal: "tfa", env: "prdo", wantErr: "no such environment of goal terraform-apply: prdo. Did you mean prod? Available environments: [dev, prod]"},
+		{name: "env required", goal: "tfa", wantErr: "goal terraform-apply requires environment, specify one of [dev, prod] with --on"},
+		{name: "no environments", goal: "lint", env: "dev", wantErr: "goal lint has no environments, remove --on dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := goals.Resolve(tt.goal, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if goalTitle(*got) != tt.want {
+				t.Errorf("Resolve() = %v, want %v", goalTitle(*got), tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"lint", "lint", 0},
+		{"lnit", "lint", 2},
+		{"aply", "apply", 1},
+		{"", "test", 4},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommands_aliasOfAnotherGoal(t *testing.T) {
+	_, err := ParseCommands([]byte("apply:\n  cmd: terraform\n  aliases: [plan]\nplan:\n  cmd: terraform\n"))
+	if err == nil || err.Error() != "alias plan of goal apply is a name of another goal" {
+		t.Errorf("ParseCommands() error = %v, want alias conflict", err)
+	}
+}
@@ -0,0 +1,361 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"github.com/manifoldco/promptui"
+	"os"
+	"strings"
+	"time"
+	// timezones must resolve on machines without zoneinfo, e.g. Windows
+	_ "time/tzdata"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// weekSpan is a range of minutes since Sunday 00:00, End may be less than Start when span wraps over the week
+type weekSpan struct {
+	Start int
+	End   int
+}
+
+func (s weekSpan) contains(minute int) bool {
+	if s.Start <= s.End {
+		return minute >= s.Start && minute < s.End
+	}
+	return minute >= s.Start || minute < s.End
+}
+
+func minuteOfWeek(t time.Time) int {
+	return int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute()
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	day, ok := weekdays[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday %q, expected one of mon, tue, wed, thu, fri, sat, sun", s)
+	}
+	return day, nil
+}
+
+// parseWeekTime parses "fri 16:00" into minutes since Sunday 00:00. ok is false when s has no weekday.
+func parseWeekTime(s string) (minute int, ok bool, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, false, nil
+	}
+	day, err := parseWeekday(fields[0])
+	if err != nil {
+		return 0, true, err
+	}
+	clock, err := parseClock(fields[1])
+	if err != nil {
+		return 0, true, err
+	}
+	return int(day)*minutesPerDay + clock, true, nil
+}
+
+func parseScheduleWindow(w YamlScheduleWindow) ([]weekSpan, error) {
+	from, weekly, err := parseWeekTime(w.From)
+	if err != nil {
+		return nil, err
+	}
+	if weekly {
+		if len(w.Days) > 0 {
+			return nil, errors.New("'days' could not be combined with weekday in 'from'")
+		}
+		to, ok, err := parseWeekTime(w.To)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid 'to' %q, expected weekday and time like 'mon 08:00'", w.To)
+		}
+		return []weekSpan{{Start: from, End: to}}, nil
+	}
+	fromClock, err := parseClock(w.From)
+	if err != nil {
+		return nil, err
+	}
+	toClock, err := parseClock(w.To)
+	if err != nil {
+		return nil, err
+	}
+	days := w.Days
+	if len(days) == 0 {
+		days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	}
+	var spans []weekSpan
+	for _, name := range days {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		start := int(day)*minutesPerDay + fromClock
+		length := toClock - fromClock
+		if length <= 0 {
+			// window crosses midnight, e.g. 22:00-06:00
+			length += minutesPerDay
+		}
+		spans = append(spans, weekSpan{Start: start, End: (start + length) % minutesPerWeek})
+	}
+	return spans, nil
+}
+
+// parseFreezeTime parses "2006-01-02", "2006-01-02 15:04" or yearly recurring "01-02" in loc.
+// A date without time as the end of a freeze includes the whole day.
+func parseFreezeTime(s string, year int, loc *time.Location, end bool) (t time.Time, recurring bool, err error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, loc); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+		var monthDay time.Time
+		if monthDay, err = time.ParseInLocation("01-02", s, loc); err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, YYYY-MM-DD HH:MM or MM-DD", s)
+		}
+		t, recurring = time.Date(year, monthDay.Month(), monthDay.Day(), 0, 0, 0, 0, loc), true
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, recurring, nil
+}
+
+// freezeRange returns [from, to) of freeze f nearest to t. Yearly freezes may wrap over the new year, e.g. 12-20..01-03.
+func freezeRange(f YamlFreeze, t time.Time) (time.Time, time.Time, error) {
+	from, fromRecurring, err := parseFreezeTime(f.From, t.Year(), t.Location(), false)
+	if err != nil {
+		return from, from, err
+	}
+	to, toRecurring, err := parseFreezeTime(f.To, t.Year(), t.Location(), true)
+	if err != nil {
+		return from, to, err
+	}
+	if fromRecurring != toRecurring {
+		return from, to, fmt.Errorf("freeze %s..%s mixes yearly (MM-DD) and exact dates", f.From, f.To)
+	}
+	if fromRecurring && !to.After(from) {
+		if t.Before(to) {
+			from = from.AddDate(-1, 0, 0)
+		} else {
+			to = to.AddDate(1, 0, 0)
+		}
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("freeze %s..%s ends before it starts", f.From, f.To)
+	}
+	return from, to, nil
+}
+
+func scheduleLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+// validateSchedule returns the first problem with schedule definition
+func validateSchedule(s YamlSchedule) error {
+	loc, err := scheduleLocation(s.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %s", s.Timezone, err)
+	}
+	now := time.Now().In(loc)
+	if len(s.Allow) == 0 && len(s.Block) == 0 && len(s.Freeze) == 0 {
+		return errors.New("specify at least one of 'allow', 'block' or 'freeze'")
+	}
+	for _, w := range append(append([]YamlScheduleWindow{}, s.Allow...), s.Block...) {
+		if _, err := parseScheduleWindow(w); err != nil {
+			return err
+		}
+	}
+	for _, f := range s.Freeze {
+		if _, _, err := freezeRange(f, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// === SCHEDULE
+
+// ScheduleAssertion allows runs only within Allow windows (if any), outside of Block windows and Freeze periods.
+// With Override a blocked run may proceed once user types a reason. Now is the clock, time.Now when nil.
+type ScheduleAssertion struct {
+	Timezone string
+	Allow    []YamlScheduleWindow
+	Block    []YamlScheduleWindow
+	Freeze   []YamlFreeze
+	Override bool
+	Now      func() time.Time
+}
+
+func describeWindow(w YamlScheduleWindow) string {
+	if len(w.Days) == 0 {
+		return fmt.Sprintf("%s-%s", w.From, w.To)
+	}
+	return fmt.Sprintf("%s %s-%s", strings.Join(w.Days, ","), w.From, w.To)
+}
+
+func (a ScheduleAssertion) describe() string {
+	var parts []string
+	for _, w := range a.Allow {
+		parts = append(parts, "allow "+describeWindow(w))
+	}
+	for _, w := range a.Block {
+		parts = append(parts, "block "+describeWindow(w))
+	}
+	for _, f := range a.Freeze {
+		parts = append(parts, fmt.Sprintf("freeze %s..%s", f.From, f.To))
+	}
+	desc := "schedule: " + strings.Join(parts, "; ")
+	if a.Timezone != "" {
+		desc += " (" + a.Timezone + ")"
+	}
+	return desc
+}
+
+// interactive is true with Override as user may be asked for a reason
+func (a ScheduleAssertion) interactive() bool {
+	return a.Override
+}
+
+func (a ScheduleAssertion) check(e *evaluation) Result {
+	loc, err := scheduleLocation(a.Timezone)
+	if err != nil {
+		return errored(err)
+	}
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	current := now().In(loc)
+	blocked, reason, err := a.blocked(current)
+	if err != nil {
+		return errored(err)
+	}
+	actual := current.Format("Mon 2006-01-02 15:04 MST")
+	if !blocked {
+		return Result{Status: Pass, Actual: actual}
+	}
+	if a.Override {
+		if e.prompt {
+			// stderr as it precedes the prompt, not output of the goal
+			Warn("⛔ %s", reason)
+		}
+		overrideReason, err := askOverrideReason(e.prompt)
+		if err != nil {
+			res := failed("allowed time", actual, "run the goal and type a reason to override, or set "+overrideReasonVar)
+			res.Message = fmt.Sprintf("%s (override was not confirmed: %s)", reason, err)
+			return res
+		}
+		return Result{Status: Overridden, Actual: actual, Message: overrideReason}
+	}
+	res := failed("allowed time", actual, "")
+	res.Message = reason
+	return res
+}
+
+// blocked returns whether t is outside of allowed windows or within a blocked window or freeze, and why
+func (a ScheduleAssertion) blocked(t time.Time) (bool, string, error) {
+	for _, f := range a.Freeze {
+		from, to, err := freezeRange(f, t)
+		if err != nil {
+			return false, "", err
+		}
+		if !t.Before(from) && t.Before(to) {
+			return true, withDefault(f.Message, fmt.Sprintf("Change freeze %s..%s", f.From, f.To)), nil
+		}
+	}
+	minute := minuteOfWeek(t)
+	for _, w := range a.Block {
+		in, err := windowContains(w, minute)
+		if err != nil {
+			return false, "", err
+		}
+		if in {
+			return true, withDefault(w.Message, "Runs are blocked "+describeWindow(w)), nil
+		}
+	}
+	if len(a.Allow) == 0 {
+		return false, "", nil
+	}
+	var allowed []string
+	for _, w := range a.Allow {
+		in, err := windowContains(w, minute)
+		if err != nil {
+			return false, "", err
+		}
+		if in {
+			return false, "", nil
+		}
+		allowed = append(allowed, describeWindow(w))
+	}
+	return true, "Runs are allowed only " + strings.Join(allowed, ", "), nil
+}
+
+func windowContains(w YamlScheduleWindow, minute int) (bool, error) {
+	spans, err := parseScheduleWindow(w)
+	if err != nil {
+		return false, err
+	}
+	for _, span := range spans {
+		if span.contains(minute) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func withDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// overrideReasonVar passes reason to override schedule for non-interactive runs
+const overrideReasonVar = "GOAL_OVERRIDE_REASON"
+
+// askOverrideReason takes reason from GOAL_OVERRIDE_REASON for non-interactive runs, prompts user otherwise when allowed
+func askOverrideReason(prompt bool) (string, error) {
+	if reason := strings.TrimSpace(os.Getenv(overrideReasonVar)); reason != "" {
+		return reason, nil
+	}
+	if !prompt {
+		return "", errors.New(overrideReasonVar + " is not set")
+	}
+	reasonPrompt := promptui.Prompt{
+		Label: "Type a reason to override",
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return errors.New("reason is required")
+			}
+			return nil
+		},
+	}
+	reason, err := reasonPrompt.Run()
+	return strings.TrimSpace(reason), err
+}
@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAssertion_check(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skipf("timezone database is not available: %s", err)
+	}
+	at := func(value string) func() time.Time {
+		return func() time.Time {
+			parsed, err := time.ParseInLocation("2006-01-02 15:04", value, kyiv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return parsed
+		}
+	}
+	weekend := []YamlScheduleWindow{{From: "fri 16:00", To: "mon 08:00", Message: "No prod deploys on weekends"}}
+	office := []YamlScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "08:00", To: "18:00"}}
+	holidays := []YamlFreeze{{From: "12-20", To: "01-03", Message: "Holiday freeze"}}
+	release := []YamlFreeze{{From: "2024-03-01 12:00", To: "2024-03-02"}}
+
+	tests := []struct {
+		name   string
+		assert ScheduleAssertion
+		want   Status
+	}{
+		// 2024-03-08 is Friday
+		{name: "before weekend block", assert: ScheduleAssertion{Block: weekend, Now: at("2024-03-08 15:59")}, want: Pass},
+		{name: "weekend block starts", assert: ScheduleAssertion{Block: weekend, Now: at("2024-03-08 16:00")}, want: Fail},
+		{name: "weekend block on sunday", assert: ScheduleAssertion{Block: weekend, Now: at("2024-03-10 12:00")}, want: Fail},
+		{name: "weekend block ends", assert: ScheduleAssertion{Block: weekend, Now: at("2024-03-11 08:00")}, want: Pass},
+		{name: "within office hours", assert: ScheduleAssertion{Allow: office, Now: at("2024-03-07 10:30")}, want: Pass},
+		{name: "after office hours", assert: ScheduleAssertion{Allow: office, Now: at("2024-03-07 18:00")}, want: Fail},
+		{name: "saturday", assert: ScheduleAssertion{Allow: office, Now: at("2024-03-09 10:30")}, want: Fail},
+		{name: "holiday freeze in december", assert: ScheduleAssertion{Freeze: holidays, Now: at("2024-12-24 10:00")}, want: Fail},
+		{name: "holiday freeze in january", assert: ScheduleAssertion{Freeze: holidays, Now: at("2025-01-03 23:59")}, want: Fail},
+		{name: "after holiday freeze", assert: ScheduleAssertion{Freeze: holidays, Now: at("2025-01-04 00:00")}, want: Pass},
+		{name: "release freeze", assert: ScheduleAssertion{Freeze: release, Now: at("2024-03-02 20:00")}, want: Fail},
+		{name: "before release freeze", assert: ScheduleAssertion{Freeze: release, Now: at("2024-03-01 11:59")}, want: Pass},
+		{
+			name:   "timezone is respected",
+			assert: ScheduleAssertion{Timezone: "UTC", Allow: office, Now: at("2024-03-07 10:30")},
+			want:   Pass,
+		},
+		{
+			name:   "timezone shifts window",
+			assert: ScheduleAssertion{Timezone: "UTC", Allow: office, Now: at("2024-03-07 09:30")},
+			want:   Fail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.assert.Timezone == "" {
+				tt.assert.Timezone = "Europe/Kyiv"
+			}
+			if got := tt.assert.check(newEvaluation(Goals{})); got.Status != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleAssertion_override(t *testing.T) {
+	freeze := []YamlFreeze{{From: "2024-12-20", To: "2025-01-03"}}
+	now := func() time.Time { return time.Date(2024, 12, 24, 10, 0, 0, 0, time.Local) }
+	tests := []struct {
+		name     string
+		reason   string
+		override bool
+		want     Status
+	}{
+		{name: "overridden with reason", reason: "hotfix for INC-42", override: true, want: Overridden},
+		{name: "reason is ignored without override", reason: "hotfix for INC-42", want: Fail},
+		{name: "no reason and no prompt", override: true, want: Fail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(overrideReasonVar, tt.reason)
+			assert := ScheduleAssertion{Freeze: freeze, Override: tt.override, Now: now}
+			got := assert.check(newEvaluation(Goals{}))
+			if got.Status != tt.want {
+				t.Fatalf("check() = %v, want %v", got, tt.want)
+			}
+			if tt.want == Overridden && got.Message != tt.reason {
+				t.Errorf("check() reason = %v, want %v", got.Message, tt.reason)
+			}
+		})
+	}
+}
+
+func TestGoals_Check_overridden(t *testing.T) {
+	t.Setenv(overrideReasonVar, "hotfix for INC-42")
+	goals := Goals{Commands: []Goal{{Name: "apply", Cmd: "terraform", Assert: []Assertion{
+		ScheduleAssertion{
+			Freeze:   []YamlFreeze{{From: "2024-12-20", To: "2025-01-03"}},
+			Override: true,
+			Now:      func() time.Time { return time.Date(2024, 12, 24, 10, 0, 0, 0, time.Local) },
+		},
+		ApproveAssertion{},
+	}}}}
+	report := goals.Check(goals.Commands)
+	if got := report.Rows[0]; got.Status != "OVERRIDDEN" || got.Message != "hotfix for INC-42" {
+		t.Errorf("Check() schedule = %+v, want overridden", got)
+	}
+	if got := report.Rows[1].Status; got != skipped {
+		t.Errorf("Check() approval = %v, want %v", got, skipped)
+	}
+	if code := report.ExitCode(); code != 1 {
+		t.Errorf("ExitCode() = %v, want 1", code)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule YamlSchedule
+		wantErr  bool
+	}{
+		{name: "valid", schedule: YamlSchedule{Block: []YamlScheduleWindow{{From: "fri 16:00", To: "mon 08:00"}}}},
+		{name: "empty", schedule: YamlSchedule{}, wantErr: true},
+		{name: "bad timezone", schedule: YamlSchedule{Timezone: "Mars/Olympus", Freeze: []YamlFreeze{{From: "12-20", To: "01-03"}}}, wantErr: true},
+		{name: "bad weekday", schedule: YamlSchedule{Allow: []YamlScheduleWindow{{Days: []string{"funday"}, From: "08:00", To: "18:00"}}}, wantErr: true},
+		{name: "bad time", schedule: YamlSchedule{Allow: []YamlScheduleWindow{{From: "8am", To: "18:00"}}}, wantErr: true},
+		{name: "mixed weekly span", schedule: YamlSchedule{Block: []YamlScheduleWindow{{From: "fri 16:00", To: "08:00"}}}, wantErr: true},
+		{name: "reversed freeze", schedule: YamlSchedule{Freeze: []YamlFreeze{{From: "2025-01-03", To: "2024-12-20"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSchedule(tt.schedule); (err != nil) != tt.wantErr {
+				t.Errorf("validateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// assertionDescriptions document assertion kinds in JSON Schema, one per availableAssertions
+var assertionDescriptions = map[string]string{
+	"ref":                 "Run another goal and compare its output with 'expect'",
+	"terraform_workspace": "Current terraform workspace",
+	"kubectl_context":     "Current kubectl context",
+	"gcloud_project":      "Current gcloud project",
+	"approval":            "Ask for manual approval, must be 'yes'",
+	"env_var":             "Environment variable is set or matches expected value",
+	"file":                "File exists, is absent, contains text or has checksum",
+	"tool_version":        "Installed tool version satisfies constraint, e.g. 'terraform >= 1.3, < 2'",
+	"kubectl_namespace":   "Namespace of current kubectl context",
+	"kubectl_server":      "API server and CA fingerprint of current kubectl context",
+	"schedule":            "Runs are allowed only within time windows and outside of change freezes",
+	"who":                 "Operator identity is allowed",
+	"any_of":              "At least one of nested assertions passes",
+	"all_of":              "All nested assertions pass",
+	"not":                 "Nested assertion fails",
+}
+
+// assertionModifiers are YamlAssert keys which are not assertion kinds
+var assertionModifiers = map[string]string{
+	"desc":   "Description of 'ref' assertion",
+	"expect": "Expected output of 'ref' assertion",
+	"fix":    "Hint displayed when assertion fails",
+}
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema returns JSON Schema of goals file generated from Yaml* types
+func Schema() ([]byte, error) {
+	definitions := map[string]interface{}{}
+	goal := schemaOf(reflect.TypeOf(YamlGoal{}), definitions)
+	schema := map[string]interface{}{
+		"$schema":     schemaDraft,
+		"title":       "goal.yaml",
+		"description": "Goals (project scoped aliases) with assertions, see https://github.com/aaabramov/goal",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"include": withDescription(schemaOf(reflect.TypeOf([]YamlInclude{}), definitions),
+				"Goals files to include, relative to this file"),
+			"environments": withDescription(schemaOf(reflect.TypeOf(map[string]YamlEnvironment{}), definitions),
+				"Environments goals may run on"),
+		},
+		"additionalProperties": goal,
+		"definitions":          definitions,
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func withDescription(schema map[string]interface{}, description string) map[string]interface{} {
+	schema["description"] = description
+	return schema
+}
+
+// schemaOf describes t, structs are added to definitions and referenced
+func schemaOf(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), definitions)}
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "Yaml")
+		ref := map[string]interface{}{"$ref": "#/definitions/" + name}
+		if _, defined := definitions[name]; !defined {
+			// placeholder breaks recursion, e.g. YamlAssert.any_of
+			definitions[name] = nil
+			definitions[name] = structSchema(t, definitions)
+		}
+		if reflect.PtrTo(t).Implements(unmarshalerType) {
+			// scalar shorthand
+			return map[string]interface{}{"anyOf": []interface{}{map[string]interface{}{"type": "string"}, ref}}
+		}
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	inline := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			inline = true
+			continue
+		}
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		property := schemaOf(field.Type, definitions)
+		if t == yamlAssertType {
+			if description, ok := assertionDescriptions[tag[0]]; ok {
+				property = withDescription(copySchema(property), description)
+			} else if description, ok := assertionModifiers[tag[0]]; ok {
+				property = withDescription(copySchema(property), description)
+			}
+		}
+		properties[tag[0]] = property
+		if len(tag) == 1 || tag[1] != "omitempty" {
+			required = append(required, tag[0])
+		}
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if inline {
+		// assertions implemented by goal-assert-<name> plugins
+		schema["additionalProperties"] = true
+		schema["minProperties"] = 1
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for key, value := range schema {
+		copied[key] = value
+	}
+	return copied
+}
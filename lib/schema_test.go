@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// schemaFile is committed so editors can reference it, regenerate with `go run . schema > goal.schema.json`
+const schemaFile = "../goal.schema.json"
+
+func TestSchema_upToDate(t *testing.T) {
+	got, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s is out of date with Yaml* types, regenerate with `go run . schema > goal.schema.json`", schemaFile)
+	}
+}
+
+func TestSchema_describesEveryAssertion(t *testing.T) {
+	for _, kind := range availableAssertions {
+		if assertionDescriptions[kind] == "" {
+			t.Errorf("assertion %s has no description in assertionDescriptions", kind)
+		}
+	}
+	fields, _ := yamlFields(yamlAssertType)
+	for name := range fields {
+		if assertionDescriptions[name] == "" && assertionModifiers[name] == "" {
+			t.Errorf("YamlAssert.%s has no description", name)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semver is a relaxed semantic version: missing minor and patch parts default to 0
+// and build metadata is ignored
+type semver struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   string
+}
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+func parseSemver(s string) (semver, error) {
+	m := semverPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return semver{}, fmt.Errorf("invalid version %s", strconv.Quote(s))
+	}
+	var v semver
+	v.Major, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		v.Minor, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	v.Pre = m[4]
+	return v, nil
+}
+
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1. A pre-release is lower than the same version without one.
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return comparePre(v.Pre, o.Pre)
+	}
+}
+
+// comparePre compares pre-releases by dot separated identifiers as in SemVer §11: numeric identifiers
+// numerically and lower than alphanumeric ones, which are compared as strings. More identifiers win a tie.
+func comparePre(a string, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] != bs[i]:
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	default:
+		return 0
+	}
+}
+
+type versionCheck struct {
+	op      string
+	version semver
+	// parts is the number of version components written in the constraint, used by `~>`
+	parts int
+}
+
+func (c versionCheck) matches(v semver) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case "=", "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		// pessimistic operator as in terraform: `~> 1.3` allows 1.x starting from 1.3, `~> 1.3.2` allows 1.3.x
+		if cmp < 0 {
+			return false
+		}
+		if c.parts <= 2 {
+			return v.Major == c.version.Major
+		}
+		return v.Major == c.version.Major && v.Minor == c.version.Minor
+	}
+	return false
+}
+
+// versionConstraint is a disjunction (`||`) of conjunctions (`,`) of checks, e.g. `>= 1.3, < 2 || ~> 0.15`
+type versionConstraint [][]versionCheck
+
+var versionCheckPattern = regexp.MustCompile(`^(==|=|!=|>=|<=|>|<|~>)?\s*(\S+)$`)
+
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var constraint versionConstraint
+	for _, alternative := range strings.Split(s, "||") {
+		var checks []versionCheck
+		for _, part := range strings.Split(alternative, ",") {
+			part = strings.TrimSpace(part)
+			m := versionCheckPattern.FindStringSubmatch(part)
+			if m == nil {
+				return nil, fmt.Errorf("invalid version constraint %s", strconv.Quote(part))
+			}
+			version, err := parseSemver(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %s: %s", strconv.Quote(part), err)
+			}
+			op := m[1]
+			if op == "" {
+				op = "="
+			}
+			checks = append(checks, versionCheck{
+				op:      op,
+				version: version,
+				parts:   len(strings.Split(strings.SplitN(strings.TrimPrefix(m[2], "v"), "-", 2)[0], ".")),
+			})
+		}
+		constraint = append(constraint, checks)
+	}
+	return constraint, nil
+}
+
+func (c versionConstraint) matches(v semver) bool {
+	for _, checks := range c {
+		matched := true
+		for _, check := range checks {
+			if !check.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
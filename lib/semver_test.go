@@ -0,0 +1,126 @@
+package lib
+
+import "testing"
+
+func TestVersionConstraint_matches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{constraint: ">= 1.3, < 2", version: "1.3.0", want: true},
+		{constraint: ">= 1.3, < 2", version: "1.5.7", want: true},
+		{constraint: ">= 1.3, < 2", version: "1.2.9", want: false},
+		{constraint: ">= 1.3, < 2", version: "2.0.0", want: false},
+		{constraint: ">= 1.3, < 2", version: "v1.4", want: true},
+		{constraint: "1.5.7", version: "1.5.7", want: true},
+		{constraint: "!= 1.5.7", version: "1.5.7", want: false},
+		{constraint: "~> 1.3", version: "1.9.0", want: true},
+		{constraint: "~> 1.3", version: "2.0.0", want: false},
+		{constraint: "~> 1.3.2", version: "1.3.9", want: true},
+		{constraint: "~> 1.3.2", version: "1.4.0", want: false},
+		{constraint: "~> 1.3.2", version: "1.3.1", want: false},
+		{constraint: "< 1.0 || >= 3", version: "3.12.3+g3a31588", want: true},
+		{constraint: ">= 1.6", version: "1.6.0-beta1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			constraint, err := parseVersionConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("parseVersionConstraint() error = %v", err)
+			}
+			version, err := parseSemver(tt.version)
+			if err != nil {
+				t.Fatalf("parseSemver() error = %v", err)
+			}
+			if got := constraint.matches(version); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraint_invalid(t *testing.T) {
+	for _, constraint := range []string{"", ">= ", "=> 1.3", ">= one"} {
+		t.Run(constraint, func(t *testing.T) {
+			if _, err := parseVersionConstraint(constraint); err == nil {
+				t.Errorf("parseVersionConstraint(%q) expected error", constraint)
+			}
+		})
+	}
+}
+
+func TestSemver_compare(t *testing.T) {
+	tests := []struct {
+		v    string
+		o    string
+		want int
+	}{
+		{v: "1.0.0-rc.2", o: "1.0.0-rc.10", want: -1},
+		{v: "1.0.0-rc.10", o: "1.0.0-rc.2", want: 1},
+		{v: "1.0.0-alpha", o: "1.0.0-alpha.1", want: -1},
+		{v: "1.0.0-alpha.1", o: "1.0.0-alpha.beta", want: -1},
+		{v: "1.0.0-beta", o: "1.0.0-alpha.beta", want: 1},
+		{v: "1.0.0-rc.1", o: "1.0.0", want: -1},
+		{v: "1.0.0-rc.1", o: "1.0.0-rc.1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v+" "+tt.o, func(t *testing.T) {
+			v, err := parseSemver(tt.v)
+			if err != nil {
+				t.Fatalf("parseSemver() error = %v", err)
+			}
+			o, err := parseSemver(tt.o)
+			if err != nil {
+				t.Fatalf("parseSemver() error = %v", err)
+			}
+			if got := v.compare(o); got != tt.want {
+				t.Errorf("compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionProbe_extract(t *testing.T) {
+	tests := []struct {
+		name         string
+		tool         string
+		versionRegex string
+		output       string
+		want         string
+	}{
+		{
+			name:   "terraform",
+			tool:   "terraform",
+			output: `{"terraform_version":"1.5.7","platform":"darwin_arm64","provider_selections":{},"terraform_outdated":true}`,
+			want:   "1.5.7",
+		},
+		{
+			name:   "kubectl",
+			tool:   "kubectl",
+			output: `{"clientVersion":{"major":"1","minor":"28","gitVersion":"v1.28.2"},"kustomizeVersion":"v5.0.4"}`,
+			want:   "v1.28.2",
+		},
+		{name: "helm", tool: "helm", output: "v3.12.3+g3a31588", want: "3.12.3"},
+		{
+			name:   "gcloud",
+			tool:   "gcloud",
+			output: "Google Cloud SDK 446.0.0\nbq 2.0.98\ncore 2023.09.15",
+			want:   "446.0.0",
+		},
+		{name: "unknown tool", tool: "node", output: "v18.17.1", want: "18.17.1"},
+		{name: "custom regex", tool: "java", versionRegex: `version "([^"]+)"`, output: `openjdk version "17.0.8" 2023-07-18`, want: "17.0.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, extract := versionProbe(tt.tool, "", tt.versionRegex)
+			got, err := extract(tt.output)
+			if err != nil {
+				t.Fatalf("extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extract() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
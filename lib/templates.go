@@ -0,0 +1,269 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateNames are project types `goal init` generates goals for, in order goals are generated
+var TemplateNames = []string{"terraform", "kubectl", "helm", "gcloud", "go", "node"}
+
+// SchemaComment points yaml-language-server to schema of goals file, it heads generated files
+const SchemaComment = "# yaml-language-server: $schema=https://raw.githubusercontent.com/aaabramov/goal/master/goal.schema.json"
+
+// templateGoal is a generated goal, kept in a list so goals file preserves template order
+type templateGoal struct {
+	name string
+	goal YamlGoal
+}
+
+// templates generate goals for project in dir, matched to files found there
+var templates = map[string]func(dir string) []templateGoal{
+	"terraform": terraformTemplate,
+	"kubectl":   kubectlTemplate,
+	"helm":      helmTemplate,
+	"gcloud":    gcloudTemplate,
+	"go":        goTemplate,
+	"node":      nodeTemplate,
+}
+
+// templateMarkers are files which identify project type
+var templateMarkers = map[string][]string{
+	"terraform": {"*.tf"},
+	"kubectl":   {"kustomization.yaml"},
+	"helm":      {"Chart.yaml"},
+	"go":        {"go.mod"},
+	"node":      {"package.json"},
+}
+
+// DetectTemplates returns templates matching files in dir
+func DetectTemplates(dir string) []string {
+	var detected []string
+	for _, name := range TemplateNames {
+		for _, marker := range templateMarkers[name] {
+			if matches, _ := filepath.Glob(filepath.Join(dir, marker)); len(matches) > 0 {
+				detected = append(detected, name)
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// GenerateGoals renders goals file with goals of templates for project in dir
+func GenerateGoals(dir string, names []string) ([]byte, error) {
+	var goals yaml.MapSlice
+	defined := map[string]string{}
+	for _, name := range names {
+		template, known := templates[name]
+		if !known {
+			return nil, fmt.Errorf("unknown template %s, expected one of [%s]", name, strings.Join(TemplateNames, ", "))
+		}
+		for _, generated := range template(dir) {
+			if other, exists := defined[generated.name]; exists {
+				Warn("⚠️  Skipped goal %s of %s template, already generated by %s template", generated.name, name, other)
+				continue
+			}
+			defined[generated.name] = name
+			goals = append(goals, yaml.MapItem{Key: generated.name, Value: generated.goal})
+		}
+	}
+	bytes, err := yaml.Marshal(goals)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf(SchemaComment+"\n# Generated by `goal init --template %s`. Adjust assertions to match your environments.\n\n", strings.Join(names, ","))
+	return append([]byte(header), bytes...), nil
+}
+
+// envFiles returns names of files matching patterns without extension, e.g. vars/dev.tfvars -> dev
+func envFiles(dir string, patterns ...string) []string {
+	var envs []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, match := range matches {
+			base := filepath.Base(match)
+			envs = append(envs, strings.TrimSuffix(base, filepath.Ext(base)))
+		}
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// envGoals creates an env per name with assertions made by assert
+func envGoals(envs []string, assert func(env string) []YamlAssert) *map[string]YamlEnvGoal {
+	goals := map[string]YamlEnvGoal{}
+	for _, env := range envs {
+		goals[env] = YamlEnvGoal{Assert: assert(env)}
+	}
+	return &goals
+}
+
+// exampleEnvs are used by templates which cannot detect environments
+var exampleEnvs = []string{"dev", "stage"}
+
+func terraformTemplate(dir string) []templateGoal {
+	goals := []templateGoal{
+		{name: "tf-init", goal: YamlGoal{Group: "Terraform", Desc: "Initialize terraform", Cmd: "terraform", Args: []string{"init"}}},
+	}
+	envs := envFiles(dir, "vars/*.tfvars")
+	for _, action := range []string{"plan", "apply"} {
+		goal := YamlGoal{Group: "Terraform", Desc: "Terraform " + action, Cmd: "terraform", Args: []string{action}}
+		if len(envs) > 0 {
+			goal.Desc += " on {{ .env }}"
+			goal.Args = append(goal.Args, "-var-file", "vars/{{ .env }}.tfvars")
+			goal.Envs = envGoals(envs, func(env string) []YamlAssert {
+				return []YamlAssert{{TerraformWorkspace: env}}
+			})
+		}
+		goals = append(goals, templateGoal{name: "tf-" + action, goal: goal})
+	}
+	return goals
+}
+
+func kubectlTemplate(dir string) []templateGoal {
+	manifests := []string{"-f", "deployment.yaml"}
+	if fileExists(filepath.Join(dir, "kustomization.yaml")) {
+		manifests = []string{"-k", "."}
+	}
+	context := func(env string) []YamlAssert {
+		return []YamlAssert{{KubectlContext: "gke_project_region_" + env}}
+	}
+	return []templateGoal{
+		{name: "k8s-diff", goal: YamlGoal{
+			Group: "Kubernetes",
+			Desc:  "kubectl diff on {{ .env }}",
+			Cmd:   "kubectl",
+			Args:  append([]string{"diff"}, manifests...),
+			Envs:  envGoals(exampleEnvs, context),
+		}},
+		{name: "k8s-apply", goal: YamlGoal{
+			Group: "Kubernetes",
+			Desc:  "kubectl apply on {{ .env }}",
+			Cmd:   "kubectl",
+			Args:  append([]string{"apply"}, manifests...),
+			Envs: envGoals(exampleEnvs, func(env string) []YamlAssert {
+				return append(context(env), YamlAssert{Approval: "yes"})
+			}),
+		}},
+	}
+}
+
+func helmTemplate(dir string) []templateGoal {
+	release := filepath.Base(dir)
+	if abs, err := filepath.Abs(dir); err == nil {
+		release = filepath.Base(abs)
+	}
+	if bytes, err := ioutil.ReadFile(filepath.Join(dir, "Chart.yaml")); err == nil {
+		var chart struct {
+			Name string `yaml:"name"`
+		}
+		if yaml.Unmarshal(bytes, &chart) == nil && chart.Name != "" {
+			release = chart.Name
+		}
+	}
+	var values []string
+	if fileExists(filepath.Join(dir, "values.yaml")) {
+		values = append(values, "-f", "values.yaml")
+	}
+	upgrade := YamlGoal{
+		Group: "Helm",
+		Desc:  "helm upgrade " + release,
+		Cmd:   "helm",
+		Args:  append([]string{"upgrade", "--install", release, "."}, values...),
+	}
+	if envs := envFiles(dir, "values/*.yaml"); len(envs) > 0 {
+		upgrade.Desc += " on {{ .env }}"
+		upgrade.Args = append(upgrade.Args, "-f", "values/{{ .env }}.yaml")
+		upgrade.Envs = envGoals(envs, func(env string) []YamlAssert {
+			return []YamlAssert{{KubectlContext: env}}
+		})
+	}
+	return []templateGoal{
+		{name: "helm-lint", goal: YamlGoal{Group: "Helm", Desc: "Lint chart", Cmd: "helm", Args: []string{"lint", "."}}},
+		{name: "helm-upgrade", goal: upgrade},
+	}
+}
+
+func gcloudTemplate(string) []templateGoal {
+	return []templateGoal{
+		{name: "gcloud-ssh", goal: YamlGoal{
+			Group: "Google Cloud",
+			Desc:  "SSH to {{ .env }}",
+			Cmd:   "gcloud",
+			Args:  []string{"compute", "ssh", "{{ .env }}-vm", "--zone=us-central1-c"},
+			Envs: envGoals(exampleEnvs, func(env string) []YamlAssert {
+				return []YamlAssert{{GcloudProject: env + "-project"}}
+			}),
+		}},
+	}
+}
+
+func goTemplate(string) []templateGoal {
+	var goals []templateGoal
+	for _, action := range []string{"build", "test", "vet"} {
+		goals = append(goals, templateGoal{name: "go-" + action, goal: YamlGoal{
+			Group: "Go",
+			Desc:  "go " + action + " every package",
+			Cmd:   "go",
+			Args:  []string{action, "./..."},
+		}})
+	}
+	return goals
+}
+
+func nodeTemplate(dir string) []templateGoal {
+	manager := nodePackageManager(dir)
+	goals := []templateGoal{
+		{name: "install", goal: YamlGoal{Group: "Node", Desc: "Install dependencies", Cmd: manager, Args: []string{"install"}}},
+	}
+	scripts, _ := npmScripts(dir)
+	for _, script := range scripts {
+		name := fmt.Sprint(script.Key)
+		goals = append(goals, templateGoal{name: name, goal: YamlGoal{
+			Group: "Node",
+			Desc:  fmt.Sprint(script.Value),
+			Cmd:   manager,
+			Args:  []string{"run", name},
+		}})
+	}
+	return goals
+}
+
+// nodePackageManager guesses package manager of node project by its lock file
+func nodePackageManager(dir string) string {
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		return "pnpm"
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		return "yarn"
+	default:
+		return "npm"
+	}
+}
+
+// npmScripts reads scripts of package.json in declaration order. JSON is decoded as YAML to keep the order.
+func npmScripts(dir string) (yaml.MapSlice, error) {
+	bytes, err := ioutil.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Scripts yaml.MapSlice `yaml:"scripts"`
+	}
+	if err := yaml.Unmarshal(bytes, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Scripts, nil
+}
@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// currentTerraformWorkspace resolves workspace the same way terraform does: TF_WORKSPACE wins over
+// the workspace stored in data directory. Falls back to `terraform workspace show` when neither is present.
+func currentTerraformWorkspace(e *evaluation) (string, error) {
+	if workspace := os.Getenv("TF_WORKSPACE"); workspace != "" {
+		return workspace, nil
+	}
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(dataDir, "environment")); err == nil {
+		return strings.TrimSpace(string(content)), nil
+	}
+	return e.probe("terraform", "workspace", "show")
+}
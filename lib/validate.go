@@ -0,0 +1,332 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem found in goals file at Line and Column
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		// e.g. file could not be read
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+var (
+	yamlAssertType  = reflect.TypeOf(YamlAssert{})
+	yamlGoalType    = reflect.TypeOf(YamlGoal{})
+	unmarshalerType = reflect.TypeOf((*yamlv2.Unmarshaler)(nil)).Elem()
+)
+
+// validator strictly checks goals file against Yaml* types, reporting every problem with its position
+type validator struct {
+	file     string
+	problems []Problem
+}
+
+func (v *validator) report(node *yaml.Node, message string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{File: v.file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(message, args...)})
+}
+
+// ValidateFile strictly validates goals file path and files it includes
+func ValidateFile(path string) []Problem {
+	return validateFile(path, map[string]bool{})
+}
+
+func validateFile(path string, visited map[string]bool) []Problem {
+	abs, err := filepath.Abs(path)
+	if err != nil || visited[abs] {
+		return nil
+	}
+	visited[abs] = true
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	problems, includes := ValidateBytes(path, bytes)
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		problems = append(problems, validateFile(include, visited)...)
+	}
+	return problems
+}
+
+// ValidateBytes strictly validates content of goals file. Returns found problems and files included by it.
+func ValidateBytes(file string, bytes []byte) ([]Problem, []string) {
+	v := &validator{file: file}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		problem := Problem{File: file, Message: err.Error()}
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			problem.Message = strings.Join(typeErr.Errors, "; ")
+		}
+		return []Problem{problem}, nil
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		v.report(root, "goals file must be a mapping of goal names to goals, got %s", nodeKind(root))
+		return v.problems, nil
+	}
+	var includes []string
+	var declaredEnvs []string
+	var goals []*yaml.Node
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], resolveAlias(root.Content[i+1])
+		if seen[key.Value] {
+			v.report(key, "goal '%s' is defined more than once", key.Value)
+		}
+		seen[key.Value] = true
+		switch key.Value {
+		case "include":
+			v.value(value, reflect.TypeOf([]YamlInclude{}), "include")
+			var parsed []YamlInclude
+			if value.Decode(&parsed) == nil {
+				for _, include := range parsed {
+					includes = append(includes, include.File)
+				}
+			}
+		case "environments":
+			v.value(value, reflect.TypeOf(map[string]YamlEnvironment{}), "environments")
+			if value.Kind == yaml.MappingNode {
+				for j := 0; j < len(value.Content); j += 2 {
+					declaredEnvs = append(declaredEnvs, value.Content[j].Value)
+				}
+			}
+		default:
+			v.value(value, yamlGoalType, key.Value)
+			goals = append(goals, key, value)
+		}
+	}
+	for i := 0; i < len(goals); i += 2 {
+		v.goal(goals[i], goals[i+1], declaredEnvs)
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+		return v.problems[i].Column < v.problems[j].Column
+	})
+	return v.problems, includes
+}
+
+// goal checks that every goal variant has cmd and uses declared environments
+func (v *validator) goal(key *yaml.Node, node *yaml.Node, declaredEnvs []string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	cmd := mappingValue(node, "cmd")
+	// cmd of a wrong type is reported by type checks
+	hasCmd := cmd != nil && (cmd.Kind != yaml.ScalarNode || cmd.Value != "")
+	envs := mappingValue(node, "envs")
+	if envs == nil || envs.Kind != yaml.MappingNode {
+		if !hasCmd {
+			v.report(key, "%s.cmd could not be empty", key.Value)
+		}
+		return
+	}
+	for i := 0; i+1 < len(envs.Content); i += 2 {
+		envKey, env := envs.Content[i], resolveAlias(envs.Content[i+1])
+		if len(declaredEnvs) > 0 && !contains(declaredEnvs, envKey.Value) {
+			v.report(envKey, "%s.%s is not declared in environments [%s]", key.Value, envKey.Value, strings.Join(declaredEnvs, ", "))
+		}
+		envCmd := mappingValue(env, "cmd")
+		if !hasCmd && (envCmd == nil || (envCmd.Kind == yaml.ScalarNode && envCmd.Value == "")) {
+			v.report(envKey, "%s.%s.cmd could not be empty", key.Value, envKey.Value)
+		}
+	}
+}
+
+// value checks that node can be decoded into t
+func (v *validator) value(node *yaml.Node, t reflect.Type, path string) {
+	node = resolveAlias(node)
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// scalar shorthand, e.g. `tool_version: terraform >= 1.3`
+	if node.Kind == yaml.ScalarNode && reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.report(node, "%s must be a string, got %s", path, nodeKind(node))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!bool" && !isYaml11Bool(node)) {
+			v.report(node, "%s must be true or false, got %s", path, nodeDisplay(node))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, "%s must be a list, got %s", path, nodeKind(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.value(item, t.Elem(), fmt.Sprintf("%s.%d", path, i))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.report(node, "%s must be a mapping, got %s", path, nodeKind(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.value(node.Content[i+1], t.Elem(), path+"."+node.Content[i].Value)
+		}
+	case reflect.Struct:
+		v.mapping(node, t, path)
+	}
+}
+
+// isYaml11Bool tells whether node is a bool for yaml.v2 goals are loaded with, e.g. `yes` or `off`
+func isYaml11Bool(node *yaml.Node) bool {
+	if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return false
+	}
+	var b bool
+	return yamlv2.Unmarshal([]byte(node.Value), &b) == nil
+}
+
+// mapping checks keys of node against yaml tags of struct t
+func (v *validator) mapping(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.report(node, "%s must be a mapping, got %s", path, nodeKind(node))
+		return
+	}
+	fields, inline := yamlFields(t)
+	seen := map[string]bool{}
+	var kinds []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if seen[key.Value] {
+			v.report(key, "key '%s' is specified more than once in %s", key.Value, path)
+		}
+		seen[key.Value] = true
+		if t == yamlAssertType && (contains(availableAssertions, key.Value) || fields[key.Value] == nil) {
+			kinds = append(kinds, key.Value)
+		}
+		field, known := fields[key.Value]
+		switch {
+		case known:
+			v.value(value, field.Type, path+"."+key.Value)
+		case inline:
+			// handled by assertion checks, e.g. plugins
+		default:
+			v.report(key, "unknown key '%s' in %s, expected one of [%s]", key.Value, path, strings.Join(fieldNames(fields), ", "))
+		}
+	}
+	if t != yamlAssertType {
+		return
+	}
+	if len(kinds) > 1 {
+		v.report(node, "%s has multiple assertion kinds [%s], specify one per entry", path, strings.Join(kinds, ", "))
+		return
+	}
+	if strings.Contains(path, ".any_of.") || strings.Contains(path, ".all_of.") || strings.HasSuffix(path, ".not") {
+		// nested assertions are checked together with their parent
+		return
+	}
+	var assert YamlAssert
+	if err := node.Decode(&assert); err != nil {
+		return
+	}
+	if problemPath, problem := assertProblem(path, assert, false); problem != "" {
+		v.report(node, "%s: %s", problemPath, problem)
+	}
+}
+
+// yamlFields maps yaml keys of struct t to its fields. inline is true when t collects unknown keys.
+func yamlFields(t reflect.Type) (map[string]*reflect.StructField, bool) {
+	fields := map[string]*reflect.StructField{}
+	inline := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			inline = true
+			continue
+		}
+		if tag[0] != "" && tag[0] != "-" {
+			fields[tag[0]] = &field
+		}
+	}
+	return fields, inline
+}
+
+func fieldNames(fields map[string]*reflect.StructField) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func nodeKind(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "list"
+	default:
+		return nodeDisplay(node)
+	}
+}
+
+func nodeDisplay(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+	return nodeKind(node)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "valid",
+			yaml: `
+include:
+  - ops.goal.yaml
+environments:
+  dev:
+    protected: false
+apply:
+  cmd: terraform
+  args: [apply]
+  assert:
+    - tool_version: terraform >= 1.3
+    - kubectl_server: https://10.0.0.1
+    - approval: yes
+  envs:
+    dev:
+      args+: [-auto-approve]
+`,
+		},
+		{
+			name: "unknown key",
+			yaml: "apply:\n  asert: []\n  cmd: terraform\n",
+			want: []string{"goal.yaml:2:3: unknown key 'asert' in apply, expected one of [aliases, args, assert, cmd, desc, dir, envs, group, hidden]"},
+		},
+		{
+			name: "wrong types",
+			yaml: "apply:\n  cmd: [terraform]\n  args: apply\n",
+			want: []string{
+				"goal.yaml:2:8: apply.cmd must be a string, got list",
+				"goal.yaml:3:9: apply.args must be a list, got 'apply'",
+			},
+		},
+		{
+			name: "yaml 1.1 bools",
+			yaml: "environments:\n  prod:\n    protected: yes\napply:\n  cmd: terraform\n  hidden: off\n  assert:\n    - file: {path: plan.out, exists: no}\n",
+		},
+		{
+			name: "quoted bool",
+			yaml: "apply:\n  cmd: terraform\n  hidden: \"yes\"\n",
+			want: []string{"goal.yaml:3:11: apply.hidden must be true or false"},
+		},
+		{
+			name: "empty cmd",
+			yaml: "apply:\n  desc: Apply\nplan:\n  envs:\n    dev:\n      desc: Plan\n",
+			want: []string{
+				"goal.yaml:1:1: apply.cmd could not be empty",
+				"goal.yaml:5:5: plan.dev.cmd could not be empty",
+			},
+		},
+		{
+			name: "multiple assertion kinds",
+			yaml: "apply:\n  cmd: kubectl\n  assert:\n    - kubectl_context: dev\n      gcloud_project: dev\n",
+			want: []string{"goal.yaml:4:7: apply.assert.0 has multiple assertion kinds [kubectl_context, gcloud_project]"},
+		},
+		{
+			name: "unknown assertion",
+			yaml: "apply:\n  cmd: kubectl\n  assert:\n    - any_of:\n        - kubectl_contex: dev\n",
+			want: []string{"goal.yaml:4:7: apply.assert.0.any_of.0: unknown assertion 'kubectl_contex'"},
+		},
+		{
+			name: "undeclared environment",
+			yaml: "environments:\n  dev: {}\napply:\n  cmd: kubectl\n  envs:\n    prod: {}\n",
+			want: []string{"goal.yaml:6:5: apply.prod is not declared in environments [dev]"},
+		},
+		{
+			name: "malformed yaml",
+			yaml: "apply:\n  cmd: [\n",
+			want: []string{"goal.yaml: yaml: line 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, _ := ValidateBytes(GoalFileName, []byte(tt.yaml))
+			if len(problems) != len(tt.want) {
+				t.Fatalf("ValidateBytes() = %v, want %v", problems, tt.want)
+			}
+			for i, problem := range problems {
+				if !strings.HasPrefix(problem.String(), tt.want[i]) {
+					t.Errorf("ValidateBytes() = %v, want %v", problem, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFile_includes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, GoalFileName), "include: [ops.goal.yaml]\ntest:\n  cmd: go\n")
+	writeFile(t, filepath.Join(dir, "ops.goal.yaml"), "include: [goal.yaml]\nlint:\n  cmd: golangci-lint\n  asert: []\n")
+
+	problems := ValidateFile(filepath.Join(dir, GoalFileName))
+	if len(problems) != 1 || problems[0].File != filepath.Join(dir, "ops.goal.yaml") || problems[0].Line != 4 {
+		t.Errorf("ValidateFile() = %v, want unknown key in included file", problems)
+	}
+}
+
+func TestValidateBytes_plugins(t *testing.T) {
+	installTestPlugin(t)
+	yaml := "apply:\n  cmd: kubectl\n  assert:\n    - vault_token: 1h\n    - asert: x\n"
+
+	problems, _ := ValidateBytes(GoalFileName, []byte(yaml))
+	if len(problems) != 1 || !strings.HasPrefix(problems[0].String(), "goal.yaml:5:7: apply.assert.1: unknown assertion 'asert'") {
+		t.Errorf("ValidateBytes() = %v, want only unknown assertion 'asert'", problems)
+	}
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// toolVersionProbe knows how to ask a tool for its version and how to find the version in the output
+type toolVersionProbe struct {
+	Args    []string
+	Extract func(output string) (string, error)
+}
+
+// defaultVersionRegex finds the first version-like token in output. Capture group 1 is the version.
+const defaultVersionRegex = `v?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?)`
+
+var knownTools = map[string]toolVersionProbe{
+	"terraform": {
+		Args: []string{"version", "-json"},
+		Extract: func(output string) (string, error) {
+			var v struct {
+				Version string `json:"terraform_version"`
+			}
+			if err := json.Unmarshal([]byte(output), &v); err != nil {
+				return "", err
+			}
+			return v.Version, nil
+		},
+	},
+	"kubectl": {
+		Args: []string{"version", "--client", "-o", "json"},
+		Extract: func(output string) (string, error) {
+			var v struct {
+				ClientVersion struct {
+					GitVersion string `json:"gitVersion"`
+				} `json:"clientVersion"`
+			}
+			if err := json.Unmarshal([]byte(output), &v); err != nil {
+				return "", err
+			}
+			return v.ClientVersion.GitVersion, nil
+		},
+	},
+	"helm": {
+		Args:    []string{"version", "--short"},
+		Extract: regexVersion(defaultVersionRegex),
+	},
+	"gcloud": {
+		Args:    []string{"version"},
+		Extract: regexVersion(`Google Cloud SDK (\S+)`),
+	},
+}
+
+// regexVersion extracts capture group 1 of pattern, or the whole match if pattern has no groups
+func regexVersion(pattern string) func(output string) (string, error) {
+	return func(output string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		m := re.FindStringSubmatch(output)
+		if m == nil {
+			return "", fmt.Errorf("no version matching %s found", pattern)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+	}
+}
+
+// versionProbe returns the command to run and the extractor for tool.
+// Custom versionCmd and versionRegex take precedence over built-in knowledge, unknown tools fall back to `tool --version`.
+func versionProbe(tool string, versionCmd string, versionRegex string) (string, []string, func(string) (string, error)) {
+	known, isKnown := knownTools[tool]
+	name, args := tool, []string{"--version"}
+	extract := regexVersion(defaultVersionRegex)
+	if isKnown {
+		args, extract = known.Args, known.Extract
+	}
+	if fields := strings.Fields(versionCmd); len(fields) > 0 {
+		name, args = fields[0], fields[1:]
+		extract = regexVersion(defaultVersionRegex)
+	}
+	if versionRegex != "" {
+		extract = regexVersion(versionRegex)
+	}
+	return name, args, extract
+}
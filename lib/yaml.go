@@ -1,33 +1,199 @@
 package lib
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
 
 type YamlAssert struct {
-	Desc               string `yaml:"desc,omitempty"`
-	Ref                string `yaml:"ref,omitempty"`
-	Expect             string `yaml:"expect,omitempty"`
-	Fix                string `yaml:"fix,omitempty"`
-	Approval           string `yaml:"approval,omitempty"`
-	TerraformWorkspace string `yaml:"terraform_workspace,omitempty"`
-	KubectlContext     string `yaml:"kubectl_context,omitempty"`
-	GcloudProject      string `yaml:"gcloud_project,omitempty"`
+	Desc               string             `yaml:"desc,omitempty"`
+	Ref                string             `yaml:"ref,omitempty"`
+	Expect             string             `yaml:"expect,omitempty"`
+	Fix                string             `yaml:"fix,omitempty"`
+	Approval           string             `yaml:"approval,omitempty"`
+	TerraformWorkspace string             `yaml:"terraform_workspace,omitempty"`
+	KubectlContext     string             `yaml:"kubectl_context,omitempty"`
+	GcloudProject      string             `yaml:"gcloud_project,omitempty"`
+	EnvVar             *YamlEnvVarAssert  `yaml:"env_var,omitempty"`
+	File               *YamlFileAssert    `yaml:"file,omitempty"`
+	ToolVersion        *YamlToolVersion   `yaml:"tool_version,omitempty"`
+	KubectlNamespace   string             `yaml:"kubectl_namespace,omitempty"`
+	KubectlServer      *YamlKubectlServer `yaml:"kubectl_server,omitempty"`
+	Schedule           *YamlSchedule      `yaml:"schedule,omitempty"`
+	Who                *YamlWho           `yaml:"who,omitempty"`
+	AnyOf              []YamlAssert       `yaml:"any_of,omitempty"`
+	AllOf              []YamlAssert       `yaml:"all_of,omitempty"`
+	Not                *YamlAssert        `yaml:"not,omitempty"`
+	// Plugins are keys of assertions implemented by goal-assert-<key> executables
+	Plugins map[string]interface{} `yaml:",inline"`
+}
+
+type YamlEnvVarAssert struct {
+	Name        string `yaml:"name"`
+	Expect      string `yaml:"expect,omitempty"`
+	ExpectRegex string `yaml:"expect_regex,omitempty"`
+	Set         bool   `yaml:"set,omitempty"`
+}
+
+type YamlFileAssert struct {
+	Path     string `yaml:"path"`
+	Exists   *bool  `yaml:"exists,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
+	Sha256   string `yaml:"sha256,omitempty"`
 }
 
 func (a YamlAssert) String() string {
 	return fmt.Sprintf("YamlAssert{desc:'%s',ref:'%s',expect:'%s',fix:'%s'}", a.Desc, a.Ref, a.Expect, a.Fix)
 }
 
+// YamlToolVersion is either a short `tool_version: terraform >= 1.3, < 2` or a full mapping
+type YamlToolVersion struct {
+	Tool         string `yaml:"tool"`
+	Constraint   string `yaml:"constraint"`
+	VersionCmd   string `yaml:"version_cmd,omitempty"`
+	VersionRegex string `yaml:"version_regex,omitempty"`
+}
+
+func (v *YamlToolVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var short string
+	if err := unmarshal(&short); err == nil {
+		fields := strings.SplitN(strings.TrimSpace(short), " ", 2)
+		v.Tool = fields[0]
+		if len(fields) > 1 {
+			v.Constraint = strings.TrimSpace(fields[1])
+		}
+		return nil
+	}
+	type plain YamlToolVersion
+	return unmarshal((*plain)(v))
+}
+
+// YamlKubectlServer is either a short `kubectl_server: https://1.2.3.4` or a mapping with CA fingerprint
+type YamlKubectlServer struct {
+	Server   string `yaml:"server,omitempty"`
+	CaSha256 string `yaml:"ca_sha256,omitempty"`
+}
+
+func (s *YamlKubectlServer) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&s.Server); err == nil {
+		return nil
+	}
+	type plain YamlKubectlServer
+	return unmarshal((*plain)(s))
+}
+
+type YamlSchedule struct {
+	Timezone string               `yaml:"timezone,omitempty"`
+	Allow    []YamlScheduleWindow `yaml:"allow,omitempty"`
+	Block    []YamlScheduleWindow `yaml:"block,omitempty"`
+	Freeze   []YamlFreeze         `yaml:"freeze,omitempty"`
+	Override bool                 `yaml:"override,omitempty"`
+}
+
+// YamlScheduleWindow is either a daily window `{days: [mon, fri], from: "08:00", to: "18:00"}`
+// or a weekly span `{from: "fri 16:00", to: "mon 08:00"}`
+type YamlScheduleWindow struct {
+	Days    []string `yaml:"days,omitempty"`
+	From    string   `yaml:"from"`
+	To      string   `yaml:"to"`
+	Message string   `yaml:"message,omitempty"`
+}
+
+// YamlFreeze is a period between two dates ("2006-01-02"), date-times ("2006-01-02 15:04")
+// or yearly recurring dates ("12-20"). A date-only To includes the whole day.
+type YamlFreeze struct {
+	From    string `yaml:"from"`
+	To      string `yaml:"to"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// YamlWho allows operator Identity (os_user, git_email, gcloud_account or aws_account)
+// matching one of Allow values or globs
+type YamlWho struct {
+	Identity string   `yaml:"identity"`
+	Allow    []string `yaml:"allow"`
+}
+
+// YamlEnvGoal overrides fields of YamlGoal for an env. ArgsAppend and AssertAppend extend the inherited ones.
+// Fields are in order envs are written in by `goal fmt`.
 type YamlEnvGoal struct {
-	Cmd    string       `yaml:"cmd"`
-	Args   []string     `yaml:"args,omitempty"`
-	Assert []YamlAssert `yaml:"assert,omitempty"`
-	Desc   string       `yaml:"desc"`
+	Desc         string       `yaml:"desc,omitempty"`
+	Cmd          string       `yaml:"cmd,omitempty"`
+	Args         []string     `yaml:"args,omitempty"`
+	ArgsAppend   []string     `yaml:"args+,omitempty"`
+	Dir          string       `yaml:"dir,omitempty"`
+	Assert       []YamlAssert `yaml:"assert,omitempty"`
+	AssertAppend []YamlAssert `yaml:"assert+,omitempty"`
 }
 
+// YamlGoal fields are in order goals are written in by `goal init` and `goal fmt`
 type YamlGoal struct {
-	Envs   *map[string]YamlEnvGoal `yaml:"envs,omitempty"`
-	Cmd    string                  `yaml:"cmd,omitempty"`
-	Args   []string                `yaml:"args,omitempty"`
-	Assert []YamlAssert            `yaml:"assert,omitempty"`
-	Desc   string                  `yaml:"desc,omitempty"`
+	Desc    string                  `yaml:"desc,omitempty"`
+	Group   string                  `yaml:"group,omitempty"`
+	Aliases []string                `yaml:"aliases,omitempty"`
+	Hidden  bool                    `yaml:"hidden,omitempty"`
+	Cmd     string                  `yaml:"cmd,omitempty"`
+	Args    []string                `yaml:"args,omitempty"`
+	Dir     string                  `yaml:"dir,omitempty"`
+	Assert  []YamlAssert            `yaml:"assert,omitempty"`
+	Envs    *map[string]YamlEnvGoal `yaml:"envs,omitempty"`
+}
+
+// YamlInclude is either a short `- ops/k8s.goal.yaml` or a mapping with namespace prefixing included goals, e.g. k8s:apply
+type YamlInclude struct {
+	File      string `yaml:"file"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+func (i *YamlInclude) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&i.File); err == nil {
+		return nil
+	}
+	type plain YamlInclude
+	return unmarshal((*plain)(i))
+}
+
+// YamlEnvironment is declared in top-level `environments` section
+type YamlEnvironment struct {
+	Desc      string       `yaml:"desc,omitempty"`
+	Color     string       `yaml:"color,omitempty"`
+	Protected bool         `yaml:"protected,omitempty"`
+	Assert    []YamlAssert `yaml:"assert,omitempty"`
+}
+
+// yamlValue defers decoding of a YAML value until it is known what it holds, e.g. goal or include list
+type yamlValue struct {
+	unmarshal func(interface{}) error
+}
+
+func (v *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	v.unmarshal = unmarshal
+	return nil
+}
+
+func (v yamlValue) decode(out interface{}) error {
+	if v.unmarshal == nil {
+		// null value
+		return nil
+	}
+	return v.unmarshal(out)
+}
+
+// keys of a mapping value in declaration order
+func (v yamlValue) keys() ([]string, error) {
+	var mapping yaml.MapSlice
+	if err := v.decode(&mapping); err != nil {
+		return nil, err
+	}
+	return mapSliceKeys(mapping), nil
+}
+
+func mapSliceKeys(mapping yaml.MapSlice) []string {
+	keys := make([]string, len(mapping))
+	for i, item := range mapping {
+		keys[i] = fmt.Sprint(item.Key)
+	}
+	return keys
 }